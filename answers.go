@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// a single answer to a `var` prompt. path is the series type label at the series type level
+// (like "all named seasons"), the entry directory at the entry level, and the season directory at the season level
+type Answer struct {
+	Level  string `json:"level"`
+	Path   string `json:"path"`
+	Option string `json:"option"`
+	Answer string `json:"answer"`
+}
+
+// Prompter reads the answers to `var` prompts from an answers file first then from stdin.
+// every accepted answer can be recorded to a file so the session can be replayed
+type Prompter struct {
+	scanner     *bufio.Scanner
+	answers     map[string]string
+	record_path string
+	recorded    []Answer
+}
+
+var prompter = new_Prompter(os.Stdin)
+
+var prompt_levels = map[int8]string{
+	0: "series_type",
+	1: "entry",
+	2: "season",
+}
+
+func new_Prompter(input io.Reader) *Prompter {
+	return &Prompter{
+		scanner: bufio.NewScanner(input),
+		answers: make(map[string]string),
+	}
+}
+
+func answer_key(level string, path string, option string) string {
+	return level + "\x00" + filepath.Clean(path) + "\x00" + option
+}
+
+// load_answers reads an answers file written by hand or recorded with --record-answers
+func (p *Prompter) load_answers(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var answers []Answer
+	if err := json.Unmarshal(data, &answers); err != nil {
+		return fmt.Errorf("invalid answers file %s: %w", path, err)
+	}
+
+	valid_options := map[string]bool{
+		"keep_ep_nums":    true,
+		"starting_ep_num": true,
+		"has_season_0":    true,
+		"naming_scheme":   true,
+	}
+	valid_levels := map[string]bool{
+		"series_type": true,
+		"entry":       true,
+		"season":      true,
+	}
+	for i, answer := range answers {
+		if !valid_levels[answer.Level] {
+			return fmt.Errorf("invalid level '%s' for answer %d in %s. must be 'series_type', 'entry', or 'season'", answer.Level, i+1, path)
+		}
+		if !valid_options[answer.Option] {
+			return fmt.Errorf("invalid option '%s' for answer %d in %s. must be 'keep_ep_nums', 'starting_ep_num', 'has_season_0', or 'naming_scheme'", answer.Option, i+1, path)
+		}
+		p.answers[answer_key(answer.Level, answer.Path, answer.Option)] = answer.Answer
+	}
+	return nil
+}
+
+// record_to makes the prompter write every accepted answer to path
+func (p *Prompter) record_to(path string) {
+	p.record_path = path
+}
+
+// ask returns the answer for an option from the answers file if there is one, otherwise it reads a line from stdin.
+// answers from the file are only used once so an invalid answer falls back to stdin instead of looping.
+// reaching the end of stdin answers 'default'
+func (p *Prompter) ask(level int8, path string, option string) string {
+	key := answer_key(prompt_levels[level], path, option)
+	if answer, ok := p.answers[key]; ok {
+		delete(p.answers, key)
+		fmt.Println(answer)
+		return answer
+	}
+
+	if p.scanner.Scan() {
+		return p.scanner.Text()
+	}
+	warn("no more input for '%s', using default", option)
+	return "default"
+}
+
+// accept records an answer that ended a prompt
+func (p *Prompter) accept(level int8, path string, option string, answer string) {
+	if p.record_path == "" {
+		return
+	}
+	p.recorded = append(p.recorded, Answer{
+		Level:  prompt_levels[level],
+		Path:   path,
+		Option: option,
+		Answer: answer,
+	})
+
+	// written after every answer so an interrupted session can still be replayed up to that point
+	data, err := json.MarshalIndent(p.recorded, "", "  ")
+	if err == nil {
+		err = os.WriteFile(p.record_path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		warn("could not record answers to %s: %s", p.record_path, err)
+	}
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypeRule describes a series or movie type: the conditions an entry must meet to be categorized as it,
+// and how the seasons, movies, and title of an entry of that type are read.
+// rules are checked in order and the first one whose conditions all hold wins
+type TypeRule struct {
+	Name string `json:"name"`
+
+	// conditions on the entry directory. conditions left empty are not checked
+	SubdirPattern string `json:"subdir_pattern"` // at least min_subdirs (default 1) subdirectories match this
+	// (or are season folders if season_folders is set)
+	MinSubdirs     int    `json:"min_subdirs"`
+	SameNameSubdir bool   `json:"same_name_subdir"` // a subdirectory has the same name as the entry
+	MovieSubdirs   string `json:"movie_subdirs"`    // "required" or "none": subdirectories that are not seasons or extras
+	MinMediaFiles  int    `json:"min_media_files"`  // media files directly under the entry
+	MaxMediaFiles  *int   `json:"max_media_files"`
+
+	// how an entry of this type is read
+	SeasonFolders bool   `json:"season_folders"` // seasons are season folders like "Season 1", "S01", "Staffel 3", or "Season II"
+	SeasonPattern string `json:"season_pattern"` // first group is the season number. without this or season_folders the entry is a single season
+	HasMovies     bool   `json:"has_movies"`     // subdirectories that are not seasons or extras are movies
+	Title         string `json:"title"`          // "entry", "season", or "entry_season"
+
+	source       string
+	subdir_regex *regexp.Regexp
+	season_regex *regexp.Regexp
+}
+
+// a series type. the built-in ones have constants, the others come from a rules file
+type SeriesKind string
+
+// a movie type. the built-in ones have constants, the others come from a rules file
+type MovieKind string
+
+const (
+	kind_named_seasons               SeriesKind = "named_seasons"
+	kind_single_season_no_movies     SeriesKind = "single_season_no_movies"
+	kind_single_season_with_movies   SeriesKind = "single_season_with_movies"
+	kind_multiple_season_no_movies   SeriesKind = "multiple_season_no_movies"
+	kind_multiple_season_with_movies SeriesKind = "multiple_season_with_movies"
+
+	kind_standalone MovieKind = "standalone"
+	kind_movie_set  MovieKind = "movie_set"
+)
+
+// how the built-in types are shown in prompts and progress output. other types are shown by name
+var kind_descriptions = map[string]string{
+	string(kind_named_seasons):               "named seasons",
+	string(kind_single_season_no_movies):     "single season with no movies",
+	string(kind_single_season_with_movies):   "single season with movies",
+	string(kind_multiple_season_no_movies):   "multiple season with no movies",
+	string(kind_multiple_season_with_movies): "multiple season with movies",
+	string(kind_standalone):                  "standalone",
+	string(kind_movie_set):                   "movie set",
+}
+
+// rules loaded from this file (if it exists) in the user config directory extend the built-in rules
+const rules_file = "gorn/rules.json"
+
+var (
+	series_extras_pattern = regexp.MustCompile(`^(?i)(specials?|extras?|o(v|n)a)`)
+	movie_extras_pattern  = regexp.MustCompile(`^(?i)specials?|extras?|trailers?|ova`)
+)
+
+var series_rules = builtin_series_rules()
+var movie_rules = builtin_movie_rules()
+
+func builtin_series_rules() []*TypeRule {
+	rules := []*TypeRule{
+		{
+			Name:           string(kind_single_season_with_movies),
+			SameNameSubdir: true,
+			HasMovies:      true,
+			Title:          "season",
+		},
+		{
+			Name:          string(kind_named_seasons),
+			SubdirPattern: `^\d+\.\s+(.*)$`,
+			SeasonPattern: `^(\d+)\..*$`,
+			Title:         "entry_season",
+		},
+		{
+			Name:          string(kind_multiple_season_with_movies),
+			SeasonFolders: true,
+			MovieSubdirs:  "required",
+			HasMovies:     true,
+			Title:         "entry",
+		},
+		{
+			Name:          string(kind_multiple_season_no_movies),
+			SeasonFolders: true,
+			MovieSubdirs:  "none",
+			Title:         "entry",
+		},
+		{
+			Name:          string(kind_single_season_no_movies),
+			MinMediaFiles: 1,
+			Title:         "entry",
+		},
+	}
+	for _, rule := range rules {
+		if err := rule.compile("built-in"); err != nil {
+			panic(err)
+		}
+	}
+	return rules
+}
+
+func builtin_movie_rules() []*TypeRule {
+	rules := []*TypeRule{
+		{
+			Name:         string(kind_movie_set),
+			MovieSubdirs: "required",
+			HasMovies:    true,
+		},
+		{
+			Name:          string(kind_standalone),
+			MinMediaFiles: 1,
+		},
+	}
+	for _, rule := range rules {
+		if err := rule.compile("built-in"); err != nil {
+			panic(err)
+		}
+	}
+	return rules
+}
+
+// compile validates the rule and compiles its patterns. source is where the rule came from, for error messages
+func (rule *TypeRule) compile(source string) error {
+	rule.source = source
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("rule with no name in %s", source)
+	}
+
+	var err error
+	rule.subdir_regex = nil
+	if rule.SubdirPattern != "" {
+		rule.subdir_regex, err = regexp.Compile(rule.SubdirPattern)
+		if err != nil {
+			return fmt.Errorf("invalid subdir_pattern for rule '%s' in %s: %w", rule.Name, source, err)
+		}
+	}
+	rule.season_regex = nil
+	if rule.SeasonPattern != "" {
+		rule.season_regex, err = regexp.Compile(rule.SeasonPattern)
+		if err != nil {
+			return fmt.Errorf("invalid season_pattern for rule '%s' in %s: %w", rule.Name, source, err)
+		}
+		if rule.season_regex.NumSubexp() < 1 {
+			return fmt.Errorf("season_pattern for rule '%s' in %s must have a group for the season number", rule.Name, source)
+		}
+		if rule.SeasonFolders {
+			return fmt.Errorf("rule '%s' in %s can only have one of season_pattern and season_folders", rule.Name, source)
+		}
+	}
+
+	if rule.MovieSubdirs != "" && rule.MovieSubdirs != "required" && rule.MovieSubdirs != "none" {
+		return fmt.Errorf("invalid movie_subdirs '%s' for rule '%s' in %s. must be 'required' or 'none'", rule.MovieSubdirs, rule.Name, source)
+	}
+	if rule.Title == "" {
+		rule.Title = "entry"
+	}
+	if rule.Title != "entry" && rule.Title != "season" && rule.Title != "entry_season" {
+		return fmt.Errorf("invalid title '%s' for rule '%s' in %s. must be 'entry', 'season', or 'entry_season'", rule.Title, rule.Name, source)
+	}
+	if rule.MinSubdirs < 0 || rule.MinMediaFiles < 0 || (rule.MaxMediaFiles != nil && *rule.MaxMediaFiles < 0) {
+		return fmt.Errorf("file counts for rule '%s' in %s must not be negative", rule.Name, source)
+	}
+	if rule.SubdirPattern == "" && !rule.SeasonFolders && !rule.SameNameSubdir && rule.MovieSubdirs == "" && rule.MinMediaFiles == 0 && rule.MaxMediaFiles == nil {
+		return fmt.Errorf("rule '%s' in %s has no conditions so it would match every entry", rule.Name, source)
+	}
+	return nil
+}
+
+// load_rules adds the series and movie rules in a rules file to the registry.
+// a rule with the same name as an existing one replaces it in place, other rules are checked before the built-in ones
+//
+//	{
+//		"series": [{"name": "discs", "subdir_pattern": "^(?i)disc\\s*\\d+", "season_pattern": "^(?i)disc\\s*(\\d+)"}],
+//		"movies": []
+//	}
+func load_rules(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var rules struct {
+		SeasonWords []string    `json:"season_words"`
+		Series      []*TypeRule `json:"series"`
+		Movies      []*TypeRule `json:"movies"`
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+
+	for _, rule := range append(rules.Series, rules.Movies...) {
+		if err := rule.compile(path); err != nil {
+			return err
+		}
+	}
+	series, err := merge_rules(series_rules, rules.Series, movie_rules)
+	if err != nil {
+		return err
+	}
+	movies, err := merge_rules(movie_rules, rules.Movies, series)
+	if err != nil {
+		return err
+	}
+	if err := add_season_words(rules.SeasonWords, path); err != nil {
+		return err
+	}
+	series_rules, movie_rules = series, movies
+	return nil
+}
+
+// load_default_rules loads the rules file in the user config directory if there is one
+func load_default_rules() error {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	path := filepath.Join(dir, filepath.FromSlash(rules_file))
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return load_rules(path)
+}
+
+// merge_rules puts the user rules before the existing ones, replacing existing rules with the same name in place.
+// names must not be shared with the other registry so an entry type is always either a series or a movie
+func merge_rules(existing []*TypeRule, user []*TypeRule, other []*TypeRule) ([]*TypeRule, error) {
+	merged := append([]*TypeRule{}, existing...)
+	added := make([]*TypeRule, 0, len(user))
+	seen := make(map[string]bool)
+	for _, rule := range user {
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule '%s' is defined more than once in %s", rule.Name, rule.source)
+		}
+		seen[rule.Name] = true
+		if find_rule(other, rule.Name) != nil {
+			return nil, fmt.Errorf("rule '%s' in %s has the same name as a rule of the other media type", rule.Name, rule.source)
+		}
+
+		replaced := false
+		for i, existing_rule := range merged {
+			if existing_rule.Name == rule.Name {
+				merged[i] = rule
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			added = append(added, rule)
+		}
+	}
+	return append(added, merged...), nil
+}
+
+func find_rule(rules []*TypeRule, name string) *TypeRule {
+	for _, rule := range rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// rule returns the rule with the season pattern, title rule, and movie handling of the series type
+func (kind SeriesKind) rule() (*TypeRule, error) {
+	if rule := find_rule(series_rules, string(kind)); rule != nil {
+		return rule, nil
+	}
+	return nil, fmt.Errorf("unknown series type: %s; series type must be one of %s", kind, strings.Join(rule_names(series_rules), ", "))
+}
+
+// rule returns the rule with the movie handling of the movie type
+func (kind MovieKind) rule() (*TypeRule, error) {
+	if rule := find_rule(movie_rules, string(kind)); rule != nil {
+		return rule, nil
+	}
+	return nil, fmt.Errorf("unknown movie type: %s; movie type must be one of %s", kind, strings.Join(rule_names(movie_rules), ", "))
+}
+
+func (kind SeriesKind) description() string {
+	return describe_kind(string(kind))
+}
+
+func (kind MovieKind) description() string {
+	return describe_kind(string(kind))
+}
+
+func describe_kind(name string) string {
+	if description, ok := kind_descriptions[name]; ok {
+		return description
+	}
+	return name
+}
+
+// series_kinds returns every series type in the order their rules are checked
+func series_kinds() []SeriesKind {
+	kinds := make([]SeriesKind, 0, len(series_rules))
+	for _, rule := range series_rules {
+		kinds = append(kinds, SeriesKind(rule.Name))
+	}
+	return kinds
+}
+
+// movie_kinds returns every movie type in the order their rules are checked
+func movie_kinds() []MovieKind {
+	kinds := make([]MovieKind, 0, len(movie_rules))
+	for _, rule := range movie_rules {
+		kinds = append(kinds, MovieKind(rule.Name))
+	}
+	return kinds
+}
+
+func rule_names(rules []*TypeRule) []string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// match checks the conditions of the rule against an entry. the reason says which subdirectories or files
+// made the rule match, or the first condition that did not hold
+func (rule *TypeRule) match(entry string, extras *regexp.Regexp) (bool, string, error) {
+	files, err := os.ReadDir(entry)
+	if err != nil {
+		return false, "", err
+	}
+
+	same_name := ""
+	matched := make([]string, 0)
+	season_folders := make([]string, 0)
+	movie_dir := ""
+	media_files := make([]string, 0)
+	for _, file := range files {
+		if !file.IsDir() {
+			if is_media_file(file.Name()) {
+				media_files = append(media_files, file.Name())
+			}
+			continue
+		}
+		if file.Name() == filepath.Base(entry) {
+			same_name = file.Name()
+			continue
+		}
+		is_match := rule.subdir_regex != nil && rule.subdir_regex.MatchString(file.Name())
+		_, is_season, _, _ := rule.season_of(file.Name())
+		if is_match {
+			matched = append(matched, file.Name())
+		}
+		if is_season && rule.SeasonFolders {
+			season_folders = append(season_folders, file.Name())
+		}
+		if !is_match && !is_season && !extras.MatchString(file.Name()) && movie_dir == "" {
+			movie_dir = file.Name()
+		}
+	}
+
+	min_subdirs := rule.MinSubdirs
+	if min_subdirs < 1 {
+		min_subdirs = 1
+	}
+
+	reasons := make([]string, 0)
+	if rule.SameNameSubdir {
+		if same_name == "" {
+			return false, "no subdirectory has the same name as the entry", nil
+		}
+		reasons = append(reasons, fmt.Sprintf("subdirectory '%s' has the same name as the entry", same_name))
+	}
+	if rule.subdir_regex != nil {
+		if len(matched) < min_subdirs {
+			return false, fmt.Sprintf("%d subdirectories match subdir_pattern '%s', needs at least %d", len(matched), rule.subdir_regex, min_subdirs), nil
+		}
+		if len(matched) == 1 {
+			reasons = append(reasons, fmt.Sprintf("subdirectory '%s' matches subdir_pattern '%s'", matched[0], rule.subdir_regex))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("subdirectories '%s' match subdir_pattern '%s'", strings.Join(matched, "', '"), rule.subdir_regex))
+		}
+	}
+	if rule.SeasonFolders {
+		if len(season_folders) < min_subdirs {
+			return false, fmt.Sprintf("%d subdirectories are season folders, needs at least %d", len(season_folders), min_subdirs), nil
+		}
+		if len(season_folders) == 1 {
+			reasons = append(reasons, fmt.Sprintf("subdirectory '%s' is a season folder", season_folders[0]))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("subdirectories '%s' are season folders", strings.Join(season_folders, "', '")))
+		}
+	}
+	switch rule.MovieSubdirs {
+	case "required":
+		if movie_dir == "" {
+			return false, "every subdirectory is a season or specials/extras so there are no movies", nil
+		}
+		reasons = append(reasons, fmt.Sprintf("subdirectory '%s' is neither a season nor specials/extras so it is a movie", movie_dir))
+	case "none":
+		if movie_dir != "" {
+			return false, fmt.Sprintf("subdirectory '%s' is neither a season nor specials/extras so it is a movie", movie_dir), nil
+		}
+		reasons = append(reasons, "every other subdirectory is a season or specials/extras")
+	}
+	if len(media_files) < rule.MinMediaFiles {
+		return false, fmt.Sprintf("%d media files directly under the entry, needs at least %d", len(media_files), rule.MinMediaFiles), nil
+	}
+	if rule.MaxMediaFiles != nil && len(media_files) > *rule.MaxMediaFiles {
+		return false, fmt.Sprintf("%d media files directly under the entry, needs at most %d", len(media_files), *rule.MaxMediaFiles), nil
+	}
+	if rule.MinMediaFiles > 0 {
+		reasons = append(reasons, fmt.Sprintf("media file '%s' is directly under the entry", media_files[0]))
+	} else if rule.MaxMediaFiles != nil {
+		reasons = append(reasons, fmt.Sprintf("%d media files directly under the entry", len(media_files)))
+	}
+	return true, strings.Join(reasons, " and "), nil
+}
+
+// season_of returns the season number of a subdirectory if it is a season of an entry of this type.
+// how says why it is or is not a season
+func (rule *TypeRule) season_of(name string) (int, bool, string, error) {
+	if rule.SeasonFolders {
+		num, ok := season_folder_number(name)
+		if !ok {
+			return 0, false, "not a season folder", nil
+		}
+		return num, true, "is a season folder", nil
+	}
+	if rule.season_regex == nil {
+		return 0, false, "the entry is a single season", nil
+	}
+	match := rule.season_regex.FindStringSubmatch(name)
+	if match == nil {
+		return 0, false, fmt.Sprintf("does not match season_pattern '%s'", rule.season_regex), nil
+	}
+	// match[0] is the whole string so we only need match[1] (first matched group)
+	num, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false, "", fmt.Errorf("season number '%s' of %s is not a number", match[1], name)
+	}
+	return num, true, fmt.Sprintf("matches season_pattern '%s'", rule.season_regex), nil
+}
+
+// is_single_season reports whether entries of this type have no season subdirectories
+func (rule *TypeRule) is_single_season() bool {
+	return !rule.SeasonFolders && rule.season_regex == nil
+}
+
+// classify returns the first rule whose conditions hold for the entry, or nil if none do
+func classify(entry string, rules []*TypeRule, extras *regexp.Regexp) (*TypeRule, string, error) {
+	for _, rule := range rules {
+		matched, reason, err := rule.match(entry, extras)
+		if err != nil {
+			return nil, "", err
+		}
+		if matched {
+			trace("%s rule '%s': matches, %s", rule.source, rule.Name, reason)
+			return rule, reason, nil
+		}
+		trace("%s rule '%s': no match, %s", rule.source, rule.Name, reason)
+	}
+	return nil, "", nil
+}
+
+// title returns the default title of a season of an entry of this type
+func (rule *TypeRule) title(path string, season_path string) string {
+	switch rule.Title {
+	case "season":
+		return filepath.Base(season_path)
+	case "entry_season":
+		return filepath.Base(path) + " " + filepath.Base(season_path)
+	}
+	return filepath.Base(path)
+}
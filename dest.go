@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// library root that renamed entries are moved to (--dest). empty when renaming in place
+var dest_root string
+
+// copy entries to dest_root instead of moving them (--copy)
+var dest_copy bool
+
+// library_path is where a renamed file goes in a library root, in the layout media servers expect:
+// <root>/series/<title> (<year>)/Season XX/<new name> and <root>/movies/<name> (<year>)/<new name>.
+// movies of a series get their own movie directory
+func library_path(root string, entry string, is_movie bool, op RenameOp) string {
+	name := filepath.Base(op.new)
+	if !is_movie && op.season >= 0 {
+		return filepath.Join(root, "series", series_dir_name(entry), fmt.Sprintf("Season %02d", op.season), name)
+	}
+
+	movie := strings.TrimSuffix(name, filepath.Ext(name))
+	// the year is usually only in the name of the movie's directory or file since it is left out of the new name
+	year := parse_release_name(filepath.Base(filepath.Dir(op.old))).year
+	if year == -1 {
+		year = parse_release_name(filepath.Base(op.old)).year
+	}
+	if year != -1 && !strings.Contains(movie, fmt.Sprintf("(%d)", year)) {
+		movie = fmt.Sprintf("%s (%d)", movie, year)
+	}
+	return filepath.Join(root, "movies", movie, name)
+}
+
+// series_dir_name is the title of a series entry followed by its year and provider ids in the --tag-style
+func series_dir_name(entry string) string {
+	name := filepath.Base(entry)
+	return parse_media_tags(name).tagged_name(clean_title(name), tag_style_for(none[string]()))
+}
+
+// dest_ops moves the planned renames of an entry into dest_root
+func dest_ops(entry string, is_movie bool, ops []RenameOp) []RenameOp {
+	moved := make([]RenameOp, 0, len(ops))
+	for _, op := range ops {
+		op.new = library_path(dest_root, entry, is_movie, op)
+		op.copy = dest_copy
+		moved = append(moved, op)
+	}
+	return moved
+}
+
+// remove_moved_dirs removes the directories of an entry left empty after its files were moved to dest_root
+func remove_moved_dirs(entry string, ops []RenameOp) {
+	for _, op := range ops {
+		if !op.skip && !op.copy {
+			remove_empty_dirs(filepath.Dir(op.old), filepath.Dir(entry))
+		}
+	}
+}
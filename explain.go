@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// explain_entry traces how a single entry is classified and renamed without renaming anything.
+// args are the entry path followed by an optional --movie and any of the usual flags except the directory flags.
+//
+//	gorn explain path/to/series/entry -s0 all yes
+//	gorn explain path/to/movies/entry --movie
+func explain_entry(args []string) error {
+	if len(args) < 1 || args[0][0] == '-' {
+		return fmt.Errorf("missing entry path for 'explain'")
+	}
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return fmt.Errorf("entry %s is not a directory", path)
+	}
+
+	is_movie := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args[1:] {
+		if arg == "--movie" {
+			is_movie = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	// the entry's parent stands in as the series/movies directory so the usual flags are validated the same way
+	dir_flag := "-s"
+	if is_movie {
+		dir_flag = "-m"
+	}
+	parsed, err := parse_args(append([]string{dir_flag, filepath.Dir(path)}, rest...))
+	if err != nil {
+		return err
+	}
+
+	// traces of one entry are read in order, so nothing runs at the same time
+	jobs = 1
+	trace_output = os.Stdout
+	defer func() { trace_output = nil }()
+
+	fmt.Println("explain:", path)
+	if is_movie {
+		return explain_movie(path, parsed)
+	}
+	return explain_series(path, parsed)
+}
+
+func explain_series(path string, args Args) error {
+	fmt.Println("\nsplit_by_type:")
+	series := Series{overrides: args.type_overrides}
+	err := series.split_by_type([]string{path})
+	if err != nil {
+		return err
+	}
+	print_classifications(series.classifications)
+
+	kind := SeriesKind(series.classifications[0].kind)
+	if kind == "" {
+		return nil
+	}
+
+	rule, err := kind.rule()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nhas_movie:")
+	found, err := has_movie(path, rule)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\t result:", found)
+
+	// explain never renames so options left as var are not prompted for
+	options := args.options.with_defaults()
+	s0, _ := options.has_season_0.get()
+	fmt.Printf("\nfetch_series_content (%s, has_season_0: %t):\n", kind, s0)
+	if _, _, _, err = fetch_series_content(path, kind, s0); err != nil {
+		return err
+	}
+
+	trace_output = nil
+	info, err := series_rename_prereqs(path, kind, options)
+	if err != nil {
+		return err
+	}
+
+	season_nums := make([]int, 0, len(info.seasons))
+	for num := range info.seasons {
+		season_nums = append(season_nums, num)
+	}
+	sort.Ints(season_nums)
+	fmt.Println("\nseasons:")
+	for _, num := range season_nums {
+		if num == 0 && len(info.specials) > 1 {
+			fmt.Printf("\t %d: '%s'\n", num, strings.Join(info.specials, "', '"))
+			continue
+		}
+		fmt.Printf("\t %d: '%s'\n", num, info.seasons[num])
+	}
+	fmt.Println("movies:")
+	for _, movie := range info.movies {
+		fmt.Printf("\t '%s'\n", movie)
+	}
+
+	ops, err := info.plan()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nrenames:")
+	print_rename_ops(ops)
+	return nil
+}
+
+func explain_movie(path string, args Args) error {
+	fmt.Println("\nsplit_by_type:")
+	movies := Movies{overrides: args.type_overrides}
+	err := movies.split_by_type([]string{path})
+	if err != nil {
+		return err
+	}
+	print_classifications(movies.classifications)
+
+	kind := MovieKind(movies.classifications[0].kind)
+	if kind == "" {
+		return nil
+	}
+
+	trace_output = nil
+	info, err := movie_rename_prereqs(path, kind)
+	if err != nil {
+		return err
+	}
+	ops, err := info.plan()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nrenames:")
+	print_rename_ops(ops)
+	return nil
+}
+
+// print_rename_ops lists the planned renames grouped by season. movies of a series are grouped last
+func print_rename_ops(ops []RenameOp) {
+	season := -2
+	for _, op := range ops {
+		if op.season != season {
+			season = op.season
+			if season == -1 {
+				fmt.Println("\t movies:")
+			} else {
+				fmt.Printf("\t season %d:\n", season)
+			}
+		}
+		if op.is_noop() {
+			fmt.Printf("\t\t %s (already has its new name)\n", filepath.Base(op.old))
+			continue
+		}
+		fmt.Printf("\t\t %s -> %s\n", filepath.Base(op.old), filepath.Base(op.new))
+	}
+	if len(ops) == 0 {
+		fmt.Println("\t nothing to rename")
+	}
+}
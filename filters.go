@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Filter is an --include or --exclude pattern matched against the name of an entry or a media file.
+// patterns are globs unless prefixed with "re:", then they are regular expressions
+type Filter struct {
+	pattern string
+	regex   *regexp.Regexp
+	exclude bool
+}
+
+// filters applied to series and movie entries (--include, --exclude) and to their media files (--include-files, --exclude-files)
+var (
+	entry_filters []Filter
+	file_filters  []Filter
+)
+
+func new_filter(pattern string, exclude bool) (Filter, error) {
+	filter := Filter{pattern: pattern, exclude: exclude}
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid regex '%s': %s", expr, err)
+		}
+		filter.regex = regex
+	} else if _, err := filepath.Match(pattern, ""); err != nil {
+		return Filter{}, fmt.Errorf("invalid glob '%s': %s", pattern, err)
+	}
+	return filter, nil
+}
+
+func (f Filter) matches(name string) bool {
+	if f.regex != nil {
+		return f.regex.MatchString(name)
+	}
+	matched, _ := filepath.Match(f.pattern, name)
+	return matched
+}
+
+// passes_filters reports whether a name is kept by the filters: it must match an include pattern if there are any,
+// and must not match any exclude pattern
+func passes_filters(name string, filters []Filter) bool {
+	has_include, included := false, false
+	for _, filter := range filters {
+		if filter.exclude {
+			if filter.matches(name) {
+				return false
+			}
+			continue
+		}
+		has_include = true
+		included = included || filter.matches(name)
+	}
+	return !has_include || included
+}
+
+// filter_entries drops the entries whose directory name is not kept by entry_filters
+func filter_entries(entries []string) []string {
+	if len(entry_filters) == 0 {
+		return entries
+	}
+	kept := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if passes_filters(filepath.Base(entry), entry_filters) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// is_wanted_media_file reports whether a file is a media file kept by file_filters
+func is_wanted_media_file(file string) bool {
+	if !is_media_file(file) {
+		return false
+	}
+	return passes_filters(filepath.Base(file), file_filters)
+}
+
+func set_filters(entries []Filter, files []Filter) {
+	entry_filters = entries
+	file_filters = files
+}
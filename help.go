@@ -1,6 +1,9 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func welcome_msg(version string) {
 	fmt.Println("gorn - go rename tool")
@@ -24,6 +27,28 @@ func help(flag string) {
 		help_ken(false)
 		help_sen(false)
 		help_ns(false)
+		help_tui(false)
+		help_answers(false)
+		help_record_answers(false)
+		help_type(false)
+		help_explain(false)
+		help_rules(false)
+		help_specials_order(false)
+		help_specials_range(false)
+		help_depth(false)
+		help_alias(false)
+		help_category(false)
+		help_include(false)
+		help_include_files(false)
+		help_state(false)
+		help_jobs(false)
+		help_link(false)
+		help_dest(false)
+		help_mns(false)
+		help_tag_style(false)
+		fmt.Println("\nCommands:")
+		help_explain_command(false)
+		help_import_scheme_command(false)
 	case "-h", "--help":
 		help_help(true)
 	case "-v", "--version":
@@ -42,6 +67,48 @@ func help(flag string) {
 		help_s0(true)
 	case "-ns", "--naming-scheme":
 		help_ns(true)
+	case "--tui":
+		help_tui(true)
+	case "--answers":
+		help_answers(true)
+	case "--record-answers":
+		help_record_answers(true)
+	case "-t", "--type":
+		help_type(true)
+	case "--explain":
+		help_explain(true)
+	case "--specials-order":
+		help_specials_order(true)
+	case "--specials-range":
+		help_specials_range(true)
+	case "--depth":
+		help_depth(true)
+	case "--alias":
+		help_alias(true)
+	case "--category":
+		help_category(true)
+	case "--include", "--exclude":
+		help_include(true)
+	case "--include-files", "--exclude-files":
+		help_include_files(true)
+	case "--state":
+		help_state(true)
+	case "--jobs", "-j":
+		help_jobs(true)
+	case "--link":
+		help_link(true)
+	case "--dest", "--copy":
+		help_dest(true)
+	case "--movie-naming-scheme", "-mns":
+		help_mns(true)
+	case "--tag-style", "-ts":
+		help_tag_style(true)
+	case "--rules":
+		help_rules(true)
+	case "explain":
+		help_explain_command(true)
+	case "import-scheme":
+		help_import_scheme_command(true)
 	default:
 		fmt.Printf("invalid flag: %s\n\n", flag)
 		help("")
@@ -159,7 +226,8 @@ func help_s0(verbose bool) {
 	fmt.Printf("%-60s%s", "  [--has-season-0 | -s0] <all yes/no/default | var>",
 			"Treat extras/specials/OVA/etc directory as season 0\n")
 	if verbose {
-		fmt.Println("\n  Note that if this is set, there must be only one specials/extras/OVA directory under a series entry")
+		fmt.Println("\n  Every specials/extras/OVA/ONA directory under a series entry is part of season 0, numbered one after another")
+		fmt.Println("  in the order set by --specials-order. Use --specials-range to give each kind its own range of episode numbers instead")
 		fmt.Println("\n  This is more useful if specified at the series entry level by doing")
 		fmt.Println("  'gorn -r path/to/root -s0 var'")
 		fmt.Println("  This will let gorn prompt the user at: per series type level and per series entry level")
@@ -176,7 +244,18 @@ func help_ns(verbose bool) {
 			"Change the naming scheme\n")
 	if verbose {
 		fmt.Println("\n  examples: gorn -ns default")
-		fmt.Println(`            gorn -ns "S<season_num>E<episode_num> <parent: 1,5> <parent-parent: '_(\d+)_'> <p-3: 2,5> [<self: '\.(\w+)$'>]"`)
+		fmt.Println(`            gorn -ns all "S<season_num>E<episode_num> <parent: 1,5> <parent-parent: '_(\d+)_'> <p-3: 2,5> \[<self: '\.(\w+)$'>\]"`)
+		fmt.Println("            gorn -ns preset:plex")
+		fmt.Println("\n  Presets:")
+		fmt.Println("    name episodes, specials, multi episode files, and movies the way a media server expects. movies are named <title> (<year>)")
+		fmt.Println("    followed by their edition and provider ids in the tag style of the media server")
+		fmt.Println("    a preset can also be given as the naming scheme of a single entry or season")
+		fmt.Println(`      "preset:plex": "Show - s01e02 - Episode Title", "Show - s01e02-e03"`)
+		fmt.Println(`      "preset:jellyfin": "Show S01E02 - Episode Title", "Show S01E02-E03"`)
+		fmt.Println(`      "preset:kodi": "Show S01E02 Episode Title", "Show S01E02-E03"`)
+		fmt.Println(`      "preset:emby": "Show - S01E02 - Episode Title", "Show - S01E02-E03"`)
+		fmt.Println(`      "preset:anime-absolute": "Show - 014 - Episode Title", "Show - 014-015". specials are "Show - S00E01"`)
+		fmt.Println("    the episode title is left out when the filename doesn't have one")
 		fmt.Println("\n  Naming Scheme APIs:")
 		fmt.Println("    1. <season_num>")
 		fmt.Println("       represents the season number which is based on series type, and directory structure and naming")
@@ -190,6 +269,8 @@ func help_ns(verbose bool) {
 		fmt.Println("       represents the parent directory of the media file. if no option was specified, it will copy the whole name of the parent directory")
 		fmt.Println(`       additional option is to select characters from the parent directory name.`)
 		fmt.Println(`         range: "<parent: 0,3>" which will copy the first 4 characters of the parent directory name`)
+		fmt.Println(`                "<parent: 3>" which will copy only the 4th character`)
+		fmt.Println(`                a range past the end of the name stops at its last character. if it starts past the end, the whole name is copied`)
 		fmt.Println(`         regex: "<parent: 'S(\d+)'>" which will copy the capture group "(\d+)" that is prepended by "S" from the parent directory name`)
 		fmt.Println()
 		fmt.Println("         Notes on regex:")
@@ -199,6 +280,8 @@ func help_ns(verbose bool) {
 		fmt.Println(`             ie. "S(E|\d+)" has one capture group and one part.`)
 		fmt.Println(`                 "|" inside parenthesis does not count as a part separator. only "|" outside parenthesis is part separator`)
 		fmt.Println(`             ie. "'S(E)(\d+)|S(\d+)" is invalid since the first part has 2 capture groups, even if the second part has only 1 capture group`)
+		fmt.Println(`           it can contain "<" and ">" but not single quotes`)
+		fmt.Println(`           if no part matches, the whole name is copied`)
 		fmt.Println()
 		fmt.Println(`     another additional option is going above just the parent of the current directory.`)
 		fmt.Println(`         "<parent-parent>" which will copy the parent of the parent directory`)
@@ -209,5 +292,389 @@ func help_ns(verbose bool) {
 		fmt.Println("\n    4. <self>")
 		fmt.Println("       same as parent but instead of being based on the parent directory name, it is based on the name of the media file before renaming it")
 		fmt.Println("       additional options are the same as well except for `<p-number>`. self has no short form")
+		fmt.Println("\n    5. <episode_end>")
+		fmt.Println("       last episode number of a multi episode file (S01E01-E03 or S01E01E02 in the filename)")
+		fmt.Println("       same as `<episode_num>` for single episode files. can be padded like `<episode_num>`")
+		fmt.Println("\n    6. release tokens")
+		fmt.Println("       read from the scene/release style name of the media file. these take no additional options")
+		fmt.Println("       and are empty if not found in the filename")
+		fmt.Println(`         "<release_title>": show title before the season/episode or quality tags`)
+		fmt.Println(`         "<episode_title>": episode title after the season/episode tag like "The Big Day" in "Show.S01E04.The.Big.Day.1080p"`)
+		fmt.Println(`         "<year>": release year like 2019`)
+		fmt.Println(`         "<resolution>": 480p, 720p, 1080p, 2160p, etc`)
+		fmt.Println(`         "<source>": WEB-DL, WEBRip, BluRay, HDTV, DVDRip, Remux, etc`)
+		fmt.Println(`         "<codec>": AVC, HEVC, AV1, VP9, XviD`)
+		fmt.Println(`         "<audio>": audio codec and channels like "EAC3 5.1" or "AAC"`)
+		fmt.Println(`         "<group>": release group from "-GROUP" at the end or "[Group]" at the start`)
+		fmt.Println(`         "<release_flags>": PROPER and/or REPACK`)
+		fmt.Println(`       example: "S<season_num>E<episode_num> \[<resolution> <codec>\]" --> "S01E02 [1080p HEVC]"`)
+		fmt.Println("\n    7. stream tokens")
+		fmt.Println("       read from the headers of the media file itself (matroska and mp4 only). these take no additional options")
+		fmt.Println("       and fall back to the release tokens above if the file could not be read: <video_res> to <resolution>,")
+		fmt.Println("       <video_codec> to <codec>, and <audio> to <audio>")
+		fmt.Println(`         "<video_res>": resolution class of the video stream like 1080p`)
+		fmt.Println(`         "<video_codec>": AVC, HEVC, AV1, VP9, etc`)
+		fmt.Println(`         "<hdr>": HDR10, HLG, DV (Dolby Vision), or empty for SDR`)
+		fmt.Println(`         "<audio>": audio codec and channels like "EAC3 5.1". overrides the release token of the same name`)
+		fmt.Println(`         "<audio_codec>": AAC, AC3, EAC3, DTS, TrueHD, FLAC, Opus, etc`)
+		fmt.Println(`         "<audio_channels>": channel layout like 2.0, 5.1, 7.1`)
+		fmt.Println(`         "<duration>": runtime like 45m or 1h32m`)
+		fmt.Println(`       example: "S<season_num>E<episode_num> \[<video_res> <video_codec>\]" --> "S01E02 [1080p HEVC]"`)
+		fmt.Println("\n    8. <special_kind>")
+		fmt.Println("       kind of the specials directory a season 0 file is in: Special, OVA, ONA, or Extra. empty outside season 0")
+		fmt.Println(`       example: "S<season_num>E<episode_num> <special_kind>" --> "S00E03 OVA"`)
+		fmt.Println("\n    9. <title>")
+		fmt.Println("       title of the default naming scheme, usually the entry's name without its year")
+		fmt.Println("       the year, edition, and provider ids in the entry's name are left out of it. they can be put back with:")
+		fmt.Println(`         "<imdb_id>" | "<tmdb_id>" | "<tvdb_id>": id from a tag like "[imdbid-tt0123456]" or "{imdb-tt0123456}"`)
+		fmt.Println(`         "<ids>": every provider id in the --tag-style like "[imdbid-tt0123456] [tmdbid-12345]"`)
+		fmt.Println(`         "<edition_tag>": edition in the Plex form like "{edition-Director's Cut}"`)
+		fmt.Println("\n    10. <absolute_num> | <absolute_end>")
+		fmt.Println("       episode number counted from the first episode of season 1, and the last one of a multi episode file")
+		fmt.Println("       season 0 is not counted. padded like `<episode_num>`")
+		fmt.Println("\n    11. optional groups")
+		fmt.Println("       text and tokens in [] are left out entirely when a token in them is empty, or is a range or regex that picked nothing")
+		fmt.Println("       groups can be nested. an empty token in a nested group only leaves out the nested group")
+		fmt.Println(`       example: "S<season_num>E<episode_num>[ - <episode_title>]" --> "S01E02 - The Big Day" or "S01E02" if there is no episode title`)
+		fmt.Println(`       use "\[" and "\]" for literal brackets and "\<" for a literal "<"`)
+		fmt.Println("\n    12. fallbacks")
+		fmt.Println("       tokens separated by ?? in the same <> are tried in order until one is not empty")
+		fmt.Println("       if all of them are empty, the last one is used as is")
+		fmt.Println(`       example: "<episode_title ?? parent: 'Part (\d+)' ?? self>"`)
+		fmt.Println("\n    13. filters")
+		fmt.Println("       change the value of a token after the additional options. filters are separated by | and applied in order")
+		fmt.Println(`         "upper", "lower": uppercase or lowercase every letter`)
+		fmt.Println(`         "title": uppercase the first letter of every word and lowercase the rest`)
+		fmt.Println(`         "trim": remove spaces at the start and end`)
+		fmt.Println(`         "replace:'old','new'": replace every 'old' with 'new'`)
+		fmt.Println(`         "pad:3": pad a number with zeros to 3 digits. text is left as is`)
+		fmt.Println(`         "slug": lowercase words joined by -, like "show-name-2019"`)
+		fmt.Println(`       example: "<parent-parent | replace:'_',' ' | title> S<season_num | pad:3>" --> "Fruits Basket S001"`)
+		fmt.Println(`       a value that is empty after its filters counts as empty in optional groups and fallbacks`)
+		fmt.Println("\n  Template naming schemes:")
+		fmt.Println(`    a naming scheme starting with "tmpl:" is a go text/template (https://pkg.go.dev/text/template) instead`)
+		fmt.Println("    it can be used anywhere a naming scheme can, so for a whole run or for a single entry")
+		fmt.Println("    fields:")
+		fmt.Println("      .Title: title of the default naming scheme")
+		fmt.Println("      .Season .Episode .EpisodeEnd: season and episode numbers")
+		fmt.Println("      .SeasonPad .EpisodePad: padding of the season and episode numbers")
+		fmt.Println("      .Self .Ext: name of the media file before renaming it without its extension, and its extension")
+		fmt.Println("      .Parents: names of the directories above the media file. (index .Parents 0) is the directory it is in")
+		fmt.Println("      .Info: release tokens, stream tokens, and special_kind by the same names, like .Info.resolution")
+		fmt.Println("      .Size .ModTime: size in bytes and modification time of the media file")
+		fmt.Println("    functions: upper, lower, title, trim, slug, replace OLD NEW s, pad N value, default FALLBACK value, match REGEX s")
+		fmt.Println("    and the built in functions of text/template like printf, index, and len")
+		fmt.Println(`    example: "tmpl:{{.Title}} S{{pad 2 .Season}}E{{pad 2 .Episode}}{{with .Info.episode_title}} - {{.}}{{end}}"`)
+		fmt.Println(`          --> "Show Name S01E01 - Pilot"`)
+		fmt.Println("\n  Errors in a naming scheme point at the column where the scheme went wrong:")
+		fmt.Println("    unknown token 'episode' at column 16")
+		fmt.Println("        S<season_num>E<episode>")
+		fmt.Println("                       ^")
 	}
-}
\ No newline at end of file
+}
+func help_tui(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--tui]",
+			"Review and edit the rename plan in a terminal ui before renaming\n")
+	if verbose {
+		fmt.Println("\n  Shows every categorized entry and the new name of each of its media files.")
+		fmt.Println("  Options set to 'var' start at their default values and can be changed in the ui instead of being prompted for.")
+		fmt.Println("\n  entry list:")
+		fmt.Println("    up/down    select an entry")
+		fmt.Println("    enter      show the files of the entry")
+		fmt.Println("    t          change the detected series/movie type of the entry")
+		fmt.Println("    x          exclude/include the whole entry")
+		fmt.Println("\n  entry files:")
+		fmt.Println("    up/down    select a file")
+		fmt.Println("    x          exclude/include the file")
+		fmt.Println("    e          toggle keeping episode numbers for the season of the file")
+		fmt.Println("    s          edit the starting episode number for the season of the file")
+		fmt.Println("    n          edit the naming scheme for the season of the file, new names are previewed while typing")
+		fmt.Println("    0          toggle specials/extras directory as season 0 for the entry")
+		fmt.Println("    esc        go back to the entry list")
+		fmt.Println("\n  anywhere:")
+		fmt.Println("    a          rename everything that is not excluded and exit")
+		fmt.Println("    q          exit without renaming anything")
+		fmt.Println("\n  example: gorn -r path/to/root --tui")
+	}
+}
+
+func help_answers(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--answers] path/to/answers.json",
+			"Answer 'var' prompts from a file instead of stdin\n")
+	if verbose {
+		fmt.Println("\n  Answers are matched by level, path, and option so they don't depend on the order gorn asks them in.")
+		fmt.Println("  Prompts with no answer in the file (or with an invalid one) are asked on stdin as usual.")
+		fmt.Println("\n  levels and their paths:")
+		fmt.Println(`    "series_type": the series type label, like "all named seasons"`)
+		fmt.Println(`    "entry":       the series entry directory`)
+		fmt.Println(`    "season":      the season directory`)
+		fmt.Println(`  options: "keep_ep_nums", "starting_ep_num", "has_season_0", "naming_scheme"`)
+		fmt.Println("\n  example answers file:")
+		fmt.Println(`  [`)
+		fmt.Println(`    {"level": "series_type", "path": "all named seasons", "option": "keep_ep_nums", "answer": "var"},`)
+		fmt.Println(`    {"level": "entry", "path": "/media/series/Show", "option": "has_season_0", "answer": "y"},`)
+		fmt.Println(`    {"level": "season", "path": "/media/series/Show/Season 2", "option": "starting_ep_num", "answer": "13"}`)
+		fmt.Println(`  ]`)
+		fmt.Println("\n  example: gorn -r path/to/root -o var --answers answers.json")
+	}
+}
+
+func help_record_answers(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--record-answers] path/to/answers.json",
+			"Record the answers given to 'var' prompts so the session can be replayed with --answers\n")
+	if verbose {
+		fmt.Println("\n  The file is written after every answer so an interrupted session can still be replayed up to that point.")
+		fmt.Println("\n  example: gorn -r path/to/root -o var --record-answers answers.json")
+		fmt.Println("           gorn -r path/to/root -o var --answers answers.json")
+	}
+}
+
+func help_type(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--type | -t] <glob>=<type>",
+			"Force entries matching the glob to be categorized as the given series/movie type\n")
+	if verbose {
+		fmt.Println("\n  Use this when gorn guesses the type of an entry wrong. Can be specified multiple times, the first matching glob wins.")
+		fmt.Println("  The glob is matched against the entry directory name, or the whole path if the glob contains a path separator.")
+		fmt.Println("\n  series types: named_seasons, single_season_no_movies, single_season_with_movies, multiple_season_no_movies, multiple_season_with_movies")
+		fmt.Println("  movie types:  standalone, movie_set")
+		fmt.Printf("\n  The type can also be forced by putting a '%s' file containing only the type in the entry directory.\n", type_marker_file)
+		fmt.Println("  --type flags take precedence over marker files.")
+		fmt.Println("\n  examples: gorn -r path/to/root -t \"*Gundam*=named_seasons\"")
+		fmt.Println("            gorn -r path/to/root -t \"*/anime/*=multiple_season_no_movies\" -t \"Cars*=movie_set\"")
+	}
+}
+
+func help_explain(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--explain]",
+			"Show which rule categorized each entry as its series/movie type and why\n")
+	if verbose {
+		fmt.Println("\n  Entries that matched no type are shown as well.")
+		fmt.Println("\n  example: gorn -r path/to/root --explain")
+	}
+}
+
+func help_rules(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--rules] path/to/rules.json",
+			"Add series/movie types, or change the built-in ones, with rules from a file\n")
+	if verbose {
+		fmt.Println("\n  A rule says how an entry is recognized as a type and how its seasons, movies, and title are read.")
+		fmt.Println("  Rules are checked in order and the first one whose conditions all hold wins. Rules from the file are checked")
+		fmt.Println("  before the built-in ones, and a rule with the same name as a built-in one replaces it.")
+		fmt.Printf("  Rules in '%s' under the user config directory are always loaded.\n", rules_file)
+		fmt.Println("\n  conditions (left out conditions are not checked):")
+		fmt.Println("    subdir_pattern     at least min_subdirs (default 1) subdirectories match this regex")
+		fmt.Println("    season_folders     at least min_subdirs (default 1) subdirectories are season folders")
+		fmt.Println("    min_subdirs")
+		fmt.Println("    same_name_subdir   a subdirectory has the same name as the entry")
+		fmt.Println("    movie_subdirs      'required' or 'none': subdirectories that are neither seasons nor specials/extras")
+		fmt.Println("    min_media_files    media files directly under the entry")
+		fmt.Println("    max_media_files")
+		fmt.Println("\n  reading the entry:")
+		fmt.Println("    season_folders     season folders are the seasons")
+		fmt.Println("    season_pattern     regex whose first group is the season number. without this or season_folders, the entry is a single season")
+		fmt.Println("    has_movies         subdirectories that are neither seasons nor specials/extras are movies")
+		fmt.Println("    title              'entry' (default), 'season', or 'entry_season'")
+		fmt.Println("\n  movie rules use the same conditions. has_movies means every subdirectory is a movie of a set.")
+		fmt.Println("\n  season folders are a season word followed by a number or roman numerals, or S followed by a number:")
+		fmt.Printf("    %s\n", strings.Join(builtin_season_words, ", "))
+		fmt.Println("    e.g. Season 1, Season01, S01, Series 2, Staffel 3, Saison 4, Temporada 5, Season II")
+		fmt.Println(`  more season words can be added with "season_words": ["sezonul"] in the rules file.`)
+		fmt.Println("\n  example rules.json:")
+		fmt.Println(`    {"series": [{"name": "discs", "subdir_pattern": "^(?i)disc\\s*\\d+", "min_subdirs": 2, "season_pattern": "^(?i)disc\\s*(\\d+)"}]}`)
+		fmt.Println("\n  example: gorn -r path/to/root --rules rules.json")
+		fmt.Println("           gorn explain path/to/series/entry --rules rules.json")
+	}
+}
+
+func help_specials_order(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--specials-order] <kind,kind,...>",
+			"Order in which the kinds of specials directories are numbered in season 0\n")
+	if verbose {
+		fmt.Printf("\n  kinds: %s (this is also the default order)\n", strings.Join(builtin_specials_order, ", "))
+		fmt.Println("  Kinds that are left out keep their default order after the given ones. Directories of the same kind are ordered by name.")
+		fmt.Println("\n  example: gorn -r path/to/root -s0 all yes --specials-order ova,specials")
+	}
+}
+
+func help_specials_range(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--specials-range] <size>",
+			"Give each kind of specials its own range of this many episode numbers in season 0\n")
+	if verbose {
+		fmt.Println("\n  Without this, season 0 episodes continue from one specials directory to the next.")
+		fmt.Println("  With --specials-range 100 and the default order, specials are S00E001-S00E100, OVAs S00E101-S00E200, and so on.")
+		fmt.Println("\n  example: gorn -r path/to/root -s0 all yes --specials-range 100")
+	}
+}
+
+func help_depth(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--depth] <n>",
+			"Look for series/movie subroots and entries up to n directories deep (default 1)\n")
+	if verbose {
+		fmt.Println("\n  Under a root, subroots (series, movies, and --alias names) are looked for up to n directories deep.")
+		fmt.Println("  Under a subroot, categories are looked into for entries up to n directories deep.")
+		fmt.Println("  The two are counted separately, so an entry can be up to 2n directories under a root: n to its subroot, then n more.")
+		fmt.Println("  A directory under a series subroot is a category if it is named with --category, or if it has no media files")
+		fmt.Println("  and does not look like any series type. Directories under a movies subroot are only categories if named with --category.")
+		fmt.Println("\n  example: gorn -r Media --depth 2 (finds Media/TV/Anime/<entry> and Media/TV/Western/<entry>)")
+	}
+}
+
+func help_alias(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--alias] <name>=<series | movies>",
+			"Treat directories with this name under a root as a series or movies subroot\n")
+	if verbose {
+		fmt.Println("\n  Can be specified multiple times. Names are not case sensitive.")
+		fmt.Println("  built-in series subroot names: series, shows, show, tv show, tv")
+		fmt.Println("  built-in movies subroot names: movies, movie")
+		fmt.Println("\n  example: gorn -r path/to/root --alias Anime=series --alias Films=movies")
+	}
+}
+
+func help_category(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--category] <name>",
+			"Treat directories with this name under a subroot as categories of entries instead of entries\n")
+	if verbose {
+		fmt.Println("\n  Can be specified multiple times. Names are not case sensitive. Needs --depth 2 or more to look into categories.")
+		fmt.Println("  This is needed for movie categories since a directory of movie directories is otherwise a movie set.")
+		fmt.Println("\n  example: gorn -r path/to/root --depth 2 --category Marvel --category \"Studio Ghibli\"")
+	}
+}
+
+func help_include(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--include | --exclude] <glob | re:regex>",
+			"Only rename entries whose directory name matches / does not match\n")
+	if verbose {
+		fmt.Println("\n  Can be specified multiple times. An entry is renamed if it matches any --include (when there are any)")
+		fmt.Println("  and no --exclude. Globs match the whole directory name. Regexes prefixed with 're:' match any part of it.")
+		fmt.Println("  Use a regex for names with brackets since [...] in a glob is a set of characters.")
+		fmt.Println("\n  example: gorn -r path/to/root --include \"Frieren*\" --exclude \"re:\\[WIP\\]\"")
+	}
+}
+
+func help_include_files(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--include-files | --exclude-files] <glob | re:regex>",
+			"Only rename media files whose name matches / does not match\n")
+	if verbose {
+		fmt.Println("\n  Can be specified multiple times. Works like --include and --exclude, on the file names of media files in an entry.")
+		fmt.Println("  Files left out are not renamed or counted when numbering episodes.")
+		fmt.Println("\n  example: gorn -r path/to/root --exclude-files \"*sample*\"")
+	}
+}
+
+func help_state(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--state] <path/to/state.json>",
+			"Remember renamed entries and skip them in later runs until their files change\n")
+	if verbose {
+		fmt.Println("\n  After an entry is renamed, the names, sizes, and modification times of its files are saved to the state file.")
+		fmt.Println("  Later runs with the same state file skip entries whose files are the same, without scanning or prompting for them.")
+		fmt.Println("  Entries with files that were excluded or could not be renamed are not remembered.")
+		fmt.Println("  The state file is ignored if the rules file, specials, --include-files/--exclude-files, or --type flags changed.")
+		fmt.Println("  The options each entry was renamed with are saved with it, answers to var included. An entry is scanned again")
+		fmt.Println("  if an option given now is different. Options left as var match whatever was answered before.")
+		fmt.Println("\n  Files that already have their new name are always left alone, with or without --state.")
+		fmt.Println("\n  example: gorn -r path/to/root --state gorn-state.json")
+	}
+}
+
+func help_jobs(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--jobs | -j] <n>",
+			"Scan and plan up to n entries at the same time (default 1)\n")
+	if verbose {
+		fmt.Println("\n  Finding, categorizing, and planning entries is mostly waiting on the disk, which adds up on network shares.")
+		fmt.Println("  Entries are still listed and renamed one by one in the same order as with 1 job.")
+		fmt.Println("  Series with options left as var are planned one by one so prompts are asked in the same order as with 1 job.")
+		fmt.Println("  Warnings are printed as they are found, so their order may change.")
+		fmt.Println("\n  example: gorn -r path/to/root --jobs 8")
+	}
+}
+
+func help_link(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--link] <path/to/library>",
+			"Link files under a library directory with their new names instead of renaming them\n")
+	if verbose {
+		fmt.Println("\n  The original files are not touched, so they can keep seeding. Files are hardlinked, or symlinked if the library")
+		fmt.Println("  is on another drive or filesystem. Series are linked to <library>/series/<title> (<year>)/Season XX/<new name>")
+		fmt.Println("  and movies to <library>/movies/<name> (<year>)/<new name>.")
+		fmt.Println("  Links that already exist are left alone. Links whose original file is gone, or whose entry now has other new names,")
+		fmt.Println("  are removed. The links gorn made are listed in " + link_manifest_file + " in the library directory.")
+		fmt.Println("  The library directory must not be inside a root, series, or movies directory.")
+		fmt.Println("\n  example: gorn -r path/to/downloads --link path/to/library")
+	}
+}
+
+func help_dest(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--dest] <path/to/library> [--copy]",
+			"Move, or copy with --copy, renamed files into a library directory\n")
+	if verbose {
+		fmt.Println("\n  Series are moved to <library>/series/<title> (<year>)/Season XX/<new name> and movies to <library>/movies/<name> (<year>)/<new name>.")
+		fmt.Println("  The year of a movie is read from the name of its directory or file.")
+		fmt.Println("  Directories of an entry left empty after moving are removed. With --copy the original files are kept.")
+		fmt.Println("  Files on another drive or filesystem are copied and checked before the original is removed.")
+		fmt.Println("  Files already copied in an earlier run are left alone. Cannot be used with --link.")
+		fmt.Println("  The library directory must not be inside a root, series, or movies directory.")
+		fmt.Println("\n  example: gorn -r path/to/incoming --dest path/to/library")
+	}
+}
+
+func help_mns(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--movie-naming-scheme | -mns] <naming-scheme>/default",
+			"Change the naming scheme of movies\n")
+	if verbose {
+		fmt.Println("\n  Used for standalone movies, movies of a movie set, and movies of a series.")
+		fmt.Println("  Without it, movies follow the preset of --naming-scheme if it is one and are named by their directory otherwise,")
+		fmt.Println("  keeping its year, edition, and provider ids in the --tag-style.")
+		fmt.Println("  Movie naming schemes have the same syntax as --naming-scheme without the season and episode tokens, plus:")
+		fmt.Println(`    "<title>": name of the movie's directory without its year, edition, and provider ids`)
+		fmt.Println(`    "<year>": year in the media file's name, or in the movie directory's name`)
+		fmt.Println(`    "<edition>": Director's Cut, Extended, Unrated, Theatrical, Remastered, IMAX, etc`)
+		fmt.Println(`    "<collection>": the movie set or series the movie is in. empty for standalone movies`)
+		fmt.Println(`    "<part>": number of a split movie file like 1 for "movie.cd1.mkv" or "movie.pt1.mkv",`)
+		fmt.Println(`                or of a movie directory like 2 for "Kill Bill Part 2 (2004)"`)
+		fmt.Println("\n  example: gorn -r path/to/root -mns \"[<collection> - ]<title>[ (<year>)][ {edition-<edition>}]\"")
+		fmt.Println("           gorn -r path/to/root -mns preset:jellyfin")
+	}
+}
+
+func help_tag_style(verbose bool) {
+	fmt.Printf("%-60s%s", "  [--tag-style | -ts] plex/jellyfin/emby/none",
+			"Change how the year, edition, and provider ids of movies are written\n")
+	if verbose {
+		fmt.Println("\n  Years, editions, and provider ids are read from movie and series directory names in any of these forms")
+		fmt.Println("  and written in the tag style instead of being left out. The default is jellyfin, or the tag style of the preset.")
+		fmt.Println(`    plex: "Movie (2016) {edition-Director's Cut} {imdb-tt0123456}"`)
+		fmt.Println(`    jellyfin: "Movie (2016) {edition-Director's Cut} [imdbid-tt0123456]"`)
+		fmt.Println(`    emby: "Movie (2016) {edition-Director's Cut} [imdbid=tt0123456]"`)
+		fmt.Println(`    none: "Movie (2016)"`)
+		fmt.Println("  Used by the default movie names, presets, and the <ids> and <edition_tag> tokens.")
+		fmt.Println("\n  example: gorn -r path/to/root -ts plex")
+	}
+}
+
+func help_explain_command(verbose bool) {
+	fmt.Printf("%-60s%s", "  explain path/to/entry [--movie] <flags>",
+			"Trace how a single entry is categorized and what each of its files would be renamed to, without renaming anything\n")
+	if verbose {
+		fmt.Println("\n  Shows which subdirectory matched which pattern (named_seasons_pattern, seasonal_pattern, the extras pattern),")
+		fmt.Println("  the resulting seasons and movies, and the new name of every file grouped by season.")
+		fmt.Println("  The entry is explained as a series unless --movie is given. Options left as 'var' use their default values.")
+		fmt.Println("\n  example: gorn explain path/to/series/entry -s0 all yes")
+		fmt.Println("           gorn explain path/to/movies/entry --movie")
+		fmt.Println("           gorn explain path/to/series/entry -t \"*=named_seasons\"")
+	}
+}
+
+func help_import_scheme_command(verbose bool) {
+	fmt.Printf("%-60s%s", "  import-scheme \"<format>\"",
+			"Translate a Sonarr/Radarr or FileBot naming format into a naming scheme\n")
+	if verbose {
+		fmt.Println("\n  The naming scheme is printed on its own so it can be passed to --naming-scheme.")
+		fmt.Println("  Tokens with no equivalent are left out with the separators around them and listed, along with tokens that are")
+		fmt.Println("  translated to something close. A format with no tokens that have an equivalent is an error.")
+		fmt.Println("  Directories in the format are left out since naming schemes only name files.")
+		fmt.Println("  Separators inside the braces of a Sonarr token, like {[Quality Full]} or {-Release Group}, become an optional group.")
+		fmt.Println("\n  example: gorn import-scheme \"{Series Title} - S{season:00}E{episode:00} - {Episode Title}\"")
+		fmt.Println("             --> <title> - S<season_num: 2>E<episode_num: 2> - <episode_title>")
+		fmt.Println("           gorn import-scheme \"{n} - {s00e00} - {t}\"")
+		fmt.Println("           gorn -ns all \"$(gorn import-scheme '{Series Title} - S{season:00}E{episode:00}')\"")
+	}
+}
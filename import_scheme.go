@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ImportedScheme is a Sonarr/Radarr or FileBot naming format translated into a gorn naming scheme
+type ImportedScheme struct {
+	scheme string
+	// tokens of the format with no gorn equivalent. they are left out of the scheme
+	unsupported []string
+	// tokens translated to something close but not exactly the same
+	approximated []string
+	// directories of the format before the file name. gorn only names files so these are left out
+	dirs string
+}
+
+// ImportedToken is what a token of an imported format becomes. tokens with text values can be
+// lowercased, uppercased, and have their spaces replaced like Sonarr's {series title} or {Series.Title}
+type ImportedToken struct {
+	scheme string
+	// number token whose padding is the number of 0s in the token's format like {season:00}
+	number string
+	text   bool
+	approx bool
+}
+
+// Sonarr and Radarr tokens by their lowercase name with spaces
+var sonarr_tokens = map[string]ImportedToken{
+	"series title":                    {scheme: "title", text: true},
+	"series cleantitle":               {scheme: "title", text: true, approx: true},
+	"series titleyear":                {scheme: "<title>[ (<year>)]", approx: true},
+	"series cleantitleyear":           {scheme: "<title>[ (<year>)]", approx: true},
+	"season":                          {number: "season_num"},
+	"episode":                         {number: "episode_num"},
+	"absolute episode":                {number: "absolute_num"},
+	"episode title":                   {scheme: "episode_title", text: true},
+	"episode cleantitle":              {scheme: "episode_title", text: true, approx: true},
+	"quality full":                    {scheme: "<source>[-<resolution>][ <release_flags>]", approx: true},
+	"quality title":                   {scheme: "<source>[-<resolution>]", approx: true},
+	"mediainfo videocodec":            {scheme: "video_codec", text: true},
+	"mediainfo audiocodec":            {scheme: "audio_codec", text: true},
+	"mediainfo audiochannels":         {scheme: "audio_channels", text: true},
+	"mediainfo videodynamicrange":     {scheme: "hdr", text: true, approx: true},
+	"mediainfo videodynamicrangetype": {scheme: "hdr", text: true},
+	"mediainfo simple":                {scheme: "<video_codec>[ <audio_codec>]"},
+	"release group":                   {scheme: "group", text: true},
+	"original title":                  {scheme: "self", text: true},
+	"original filename":               {scheme: "self", text: true},
+	"movie title":                     {scheme: "title", text: true},
+	"movie cleantitle":                {scheme: "title", text: true, approx: true},
+	"movie titleyear":                 {scheme: "<title>[ (<year>)]", approx: true},
+	"movie cleantitleyear":            {scheme: "<title>[ (<year>)]", approx: true},
+	"release year":                    {scheme: "year", text: true},
+}
+
+// FileBot bindings by name
+var filebot_tokens = map[string]ImportedToken{
+	"n":        {scheme: "title", text: true},
+	"s":        {scheme: "<season_num: 0>"},
+	"e":        {scheme: "<episode_num: 0>"},
+	"s00e00":   {scheme: "S<season_num: 2>E<episode_num: 2>"},
+	"sxe":      {scheme: "<season_num: 0>x<episode_num: 2>"},
+	"absolute": {scheme: "<absolute_num: 0>"},
+	"t":        {scheme: "episode_title", text: true},
+	"y":        {scheme: "year", text: true},
+	"vf":       {scheme: "video_res", text: true},
+	"vc":       {scheme: "video_codec", text: true},
+	"ac":       {scheme: "audio_codec", text: true},
+	"af":       {scheme: "audio_channels", text: true},
+	"hdr":      {scheme: "hdr", text: true},
+	"group":    {scheme: "group", text: true},
+	"source":   {scheme: "source", text: true},
+	"fn":       {scheme: "self", text: true},
+}
+
+var (
+	// Sonarr tokens can have separators before and after them that are left out with the token when it's empty
+	sonarr_token_pattern = regexp.MustCompile(`^([-\s.\[(_]*)([A-Za-z](?:[A-Za-z0-9 ._]*[A-Za-z0-9])?)(?::(0+))?([-\s.\])_]*)$`)
+	filebot_pad_pattern  = regexp.MustCompile(`^(s|e|absolute)\.pad\((\d+)\)$`)
+)
+
+// import_naming_scheme translates a Sonarr/Radarr format like "{Series Title} - S{season:00}E{episode:00}"
+// or a FileBot format like "{n} - {s00e00} - {t}" into a naming scheme.
+// tokens of both can be mixed since their names don't overlap
+func import_naming_scheme(format string) (ImportedScheme, error) {
+	var imported ImportedScheme
+	var scheme strings.Builder
+	translated_tokens := 0
+	for i := 0; i < len(format); {
+		if format[i] == '}' {
+			return imported, fmt.Errorf("unexpected '}' at column %d of '%s'", i+1, format)
+		}
+		if format[i] != '{' {
+			// only the file name is kept. directories before it are left out
+			if format[i] == '/' || format[i] == '\\' {
+				imported.dirs = format[:i+1]
+				imported.unsupported, imported.approximated = nil, nil
+				scheme.Reset()
+				translated_tokens = 0
+				i++
+				continue
+			}
+			if strings.IndexByte("[]<", format[i]) != -1 {
+				scheme.WriteByte('\\')
+			}
+			scheme.WriteByte(format[i])
+			i++
+			continue
+		}
+
+		// braces can be nested in FileBot expressions
+		depth, end := 0, -1
+		for j := i; j < len(format) && end == -1; j++ {
+			switch format[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = j
+				}
+			}
+		}
+		if end == -1 {
+			return imported, fmt.Errorf("unclosed '{' at column %d of '%s'", i+1, format)
+		}
+		token := format[i : end+1]
+		translated, ok, approx := translate_imported_token(token[1 : len(token)-1])
+		if !ok {
+			imported.unsupported = append(imported.unsupported, token)
+			translated = dropped_token
+		} else {
+			translated_tokens++
+			if approx {
+				imported.approximated = append(imported.approximated, token)
+			}
+		}
+		scheme.WriteString(translated)
+		i = end + 1
+	}
+
+	if translated_tokens == 0 {
+		return imported, fmt.Errorf("'%s' has no tokens with a gorn equivalent", format)
+	}
+	imported.scheme = trim_dropped_tokens(scheme.String())
+	if err := validate_naming_scheme(imported.scheme); err != nil {
+		return imported, fmt.Errorf("'%s' was translated to an invalid naming scheme: %s", format, err)
+	}
+	return imported, nil
+}
+
+// stands in for a token with no gorn equivalent until the separators around it are trimmed
+const dropped_token = "\x00"
+
+var (
+	dropped_bracketed_pattern = regexp.MustCompile(`\\\[\s*\x00\s*\\\]|\(\s*\x00\s*\)`)
+	dropped_start_pattern     = regexp.MustCompile(`^[-\s._]*\x00[-\s._]*`)
+	dropped_end_pattern       = regexp.MustCompile(`[-\s._]*\x00$`)
+	dropped_middle_pattern    = regexp.MustCompile(`([-\s._]+)\x00[-\s._]*`)
+)
+
+// trim_dropped_tokens removes the tokens with no gorn equivalent along with what separated them from the rest,
+// so "{n} - {Custom Formats} - {t}" is "<title> - <episode_title>" and not "<title> -  - <episode_title>"
+func trim_dropped_tokens(scheme string) string {
+	scheme = dropped_bracketed_pattern.ReplaceAllString(scheme, dropped_token)
+	scheme = dropped_start_pattern.ReplaceAllString(scheme, "")
+	scheme = dropped_end_pattern.ReplaceAllString(scheme, "")
+	scheme = dropped_middle_pattern.ReplaceAllString(scheme, "$1")
+	return strings.ReplaceAll(scheme, dropped_token, "")
+}
+
+// translate_imported_token translates what is between the braces of a token
+func translate_imported_token(token string) (scheme string, ok bool, approx bool) {
+	if imported, found := filebot_tokens[token]; found {
+		return imported.render(-1), true, imported.approx
+	}
+	if match := filebot_pad_pattern.FindStringSubmatch(token); match != nil {
+		pad, _ := strconv.Atoi(match[2])
+		number := map[string]string{"s": "season_num", "e": "episode_num", "absolute": "absolute_num"}[match[1]]
+		return fmt.Sprintf("<%s: %d>", number, pad), true, false
+	}
+
+	match := sonarr_token_pattern.FindStringSubmatch(token)
+	if match == nil {
+		return "", false, false
+	}
+	prefix, name, format, suffix := match[1], match[2], match[3], match[4]
+	separator := " "
+	if strings.Contains(name, ".") {
+		separator = "."
+	} else if strings.Contains(name, "_") {
+		separator = "_"
+	}
+	imported, found := sonarr_tokens[strings.ToLower(strings.NewReplacer(".", " ", "_", " ").Replace(name))]
+	if !found {
+		return "", false, false
+	}
+
+	var filters []string
+	if imported.text && strings.ContainsAny(name, "abcdefghijklmnopqrstuvwxyz") && name == strings.ToLower(name) {
+		filters = append(filters, "lower")
+	} else if imported.text && name == strings.ToUpper(name) {
+		filters = append(filters, "upper")
+	}
+	if imported.text && separator != " " {
+		filters = append(filters, fmt.Sprintf("replace:' ','%s'", separator))
+	}
+	pad := -1
+	if format != "" {
+		pad = len(format)
+	}
+	scheme = imported.with_filters(filters).render(pad)
+
+	// separators around a token are left out with it like the optional groups of naming schemes
+	if prefix != "" || suffix != "" {
+		escape := strings.NewReplacer("[", `\[`, "]", `\]`)
+		scheme = "[" + escape.Replace(prefix) + scheme + escape.Replace(suffix) + "]"
+	}
+	return scheme, true, imported.approx
+}
+
+func (imported ImportedToken) with_filters(filters []string) ImportedToken {
+	if len(filters) > 0 && imported.text {
+		imported.scheme += " | " + strings.Join(filters, " | ")
+	}
+	return imported
+}
+
+// render returns the naming scheme of a token. pad is the padding of number tokens, -1 for none given
+func (imported ImportedToken) render(pad int) string {
+	if imported.number != "" {
+		return fmt.Sprintf("<%s: %d>", imported.number, max(pad, 0))
+	}
+	if imported.text {
+		return "<" + imported.scheme + ">"
+	}
+	return imported.scheme
+}
+
+// import_scheme_command prints the naming scheme of a Sonarr/Radarr or FileBot format so it can be passed
+// straight to --naming-scheme. what could not be translated is reported on stderr
+//
+//	gorn -ns all "$(gorn import-scheme '{Series Title} - S{season:00}E{episode:00} - {Episode Title}')"
+func import_scheme_command(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("'import-scheme' takes one naming format enclosed in quotes")
+	}
+	imported, err := import_naming_scheme(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(imported.scheme)
+	if imported.dirs != "" {
+		fmt.Fprintf(os.Stderr, "left out the directories '%s' since naming schemes only name files\n", imported.dirs)
+	}
+	if len(imported.approximated) > 0 {
+		fmt.Fprintf(os.Stderr, "not exactly the same in gorn: %s\n", strings.Join(imported.approximated, ", "))
+	}
+	if len(imported.unsupported) > 0 {
+		fmt.Fprintf(os.Stderr, "no gorn equivalent, left out: %s\n", strings.Join(imported.unsupported, ", "))
+	}
+	return nil
+}
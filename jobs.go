@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sync"
+)
+
+// how many entries are scanned and planned at the same time (--jobs)
+var jobs = 1
+
+// prompts read from the same input, so only one job asks at a time.
+// warnings are written whole so lines of different jobs don't mix
+var (
+	prompt_lock sync.Mutex
+	warn_lock   sync.Mutex
+)
+
+// run_jobs calls job with every index from 0 to count-1 on up to `jobs` goroutines.
+// jobs write their results by index so they come out in the same order as the input.
+// the error of the lowest index is returned, the same one a loop in order would stop at
+func run_jobs(count int, job func(i int) error) error {
+	return run_jobs_on(jobs, count, job)
+}
+
+// run_jobs_on is run_jobs with a given number of goroutines
+func run_jobs_on(jobs int, count int, job func(i int) error) error {
+	errs := make([]error, count)
+	if jobs <= 1 || count <= 1 {
+		for i := 0; i < count; i++ {
+			if errs[i] = job(i); errs[i] != nil {
+				return errs[i]
+			}
+		}
+		return nil
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < count; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = job(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
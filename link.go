@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// name of the file in the link root that lists every link gorn made there, so re-runs can tell
+// their own links apart from other files and prune the ones whose source is gone
+const link_manifest_file = ".gorn-links.json"
+
+// LinkLibrary is a renamed copy of the library made of links to the original files (--link),
+// laid out like library_path
+type LinkLibrary struct {
+	root  string
+	Links map[string]LinkSource `json:"links"`
+	// entries linked in this run. links of these entries that were not made again are stale
+	linked  map[string]bool
+	current map[string]bool
+}
+
+type LinkSource struct {
+	Source string `json:"source"`
+	Entry  string `json:"entry"`
+}
+
+// the link library of the current run. nil when --link is not given
+var library *LinkLibrary
+
+func load_link_library(root string) (*LinkLibrary, error) {
+	loaded := &LinkLibrary{
+		root:    root,
+		Links:   make(map[string]LinkSource),
+		linked:  make(map[string]bool),
+		current: make(map[string]bool),
+	}
+	data, err := os.ReadFile(filepath.Join(root, link_manifest_file))
+	if os.IsNotExist(err) {
+		return loaded, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		return nil, fmt.Errorf("invalid link manifest %s: %s", filepath.Join(root, link_manifest_file), err)
+	}
+	if loaded.Links == nil {
+		loaded.Links = make(map[string]LinkSource)
+	}
+	return loaded, nil
+}
+
+// link_ops turns the planned renames of an entry into links in the library
+func (l *LinkLibrary) link_ops(entry string, is_movie bool, ops []RenameOp) []RenameOp {
+	linked := make([]RenameOp, 0, len(ops))
+	for _, op := range ops {
+		op.new = library_path(l.root, entry, is_movie, op)
+		op.link = true
+		linked = append(linked, op)
+	}
+	return linked
+}
+
+// link_entry links every planned file of an entry that was not skipped and remembers the links in the manifest
+func (l *LinkLibrary) link_entry(entry string, ops []RenameOp) error {
+	l.linked[entry] = true
+	for _, op := range ops {
+		if op.skip {
+			continue
+		}
+		source, err := filepath.Abs(op.old)
+		if err != nil {
+			return err
+		}
+
+		if is_linked(source, op.new) {
+			l.track(op.new, source, entry)
+			continue
+		}
+		if _, err := os.Lstat(op.new); err == nil {
+			// links made by gorn to another file are replaced. anything else is left alone
+			if _, ok := l.Links[op.new]; !ok {
+				fmt.Println("linking", filepath.Base(op.old), "to", op.new, "failed: file already exists")
+				continue
+			}
+			if err := os.Remove(op.new); err != nil {
+				return err
+			}
+		}
+
+		fmt.Println(fmt.Sprintf("%-*s", 20, filepath.Base(op.old)), " ==> ", op.new)
+		if err := link_file(source, op.new); err != nil {
+			return err
+		}
+		l.track(op.new, source, entry)
+	}
+	return l.save()
+}
+
+func (l *LinkLibrary) track(link string, source string, entry string) {
+	l.Links[link] = LinkSource{Source: source, Entry: entry}
+	l.current[link] = true
+}
+
+// prune removes links whose source is gone, and links of entries linked in this run that were not made again
+// (like after a file got another episode number). directories left empty are removed too
+func (l *LinkLibrary) prune() error {
+	links := make([]string, 0, len(l.Links))
+	for link := range l.Links {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+
+	for _, link := range links {
+		source := l.Links[link]
+		_, err := os.Stat(source.Source)
+		stale := os.IsNotExist(err) || (l.linked[source.Entry] && !l.current[link])
+		if !stale {
+			continue
+		}
+		fmt.Println("removing stale link", link)
+		if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(l.Links, link)
+		remove_empty_dirs(filepath.Dir(link), l.root)
+	}
+	return l.save()
+}
+
+func (l *LinkLibrary) save() error {
+	if err := os.MkdirAll(l.root, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(l.root, link_manifest_file), data, 0644)
+}
+
+// link_file hardlinks source to link, or symlinks it if they are on different devices
+func link_file(source string, link string) error {
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return err
+	}
+	err := os.Link(source, link)
+	if err != nil && is_cross_device(err) {
+		err = os.Symlink(source, link)
+	}
+	return err
+}
+
+// is_linked reports whether link is a hardlink of source or a symlink to it
+func is_linked(source string, link string) bool {
+	info, err := os.Lstat(link)
+	if err != nil {
+		return false
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(link)
+		return err == nil && target == source
+	}
+	source_info, err := os.Stat(source)
+	return err == nil && os.SameFile(source_info, info)
+}
+
+// remove_empty_dirs removes dir and its parents while they are empty, stopping at root
+func remove_empty_dirs(dir string, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -14,6 +14,26 @@ func main() {
 		return
 	}
 
+	err := load_default_rules()
+	if err != nil {
+		panic(err)
+	}
+
+	if os.Args[1] == "explain" {
+		err := explain_entry(os.Args[2:])
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+	if os.Args[1] == "import-scheme" {
+		err := import_scheme_command(os.Args[2:])
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	args, err := parse_args(os.Args[1:])
 	if err != nil {
 		if err.Error() != "safe exit" {
@@ -22,6 +42,32 @@ func main() {
 		return
 	}
 
+	if args.answers != "" {
+		err = prompter.load_answers(args.answers)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if args.record_answers != "" {
+		prompter.record_to(args.record_answers)
+	}
+	if args.link != "" {
+		library, err = load_link_library(args.link)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if args.state != "" {
+		settings, err := state_settings(args)
+		if err != nil {
+			panic(err)
+		}
+		state, err = load_state(args.state, settings, args.options)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	if len(args.root) > 0 {
 		fmt.Println("roots:")
 		for _, root := range args.root {
@@ -57,6 +103,19 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	// entries left unchanged since they were renamed in a previous run are not scanned again
+	found := len(series_entries) + len(movie_entries)
+	series_entries, err = state.changed_entries(series_entries)
+	if err != nil {
+		panic(err)
+	}
+	movie_entries, err = state.changed_entries(movie_entries)
+	if err != nil {
+		panic(err)
+	}
+	if unchanged := found - len(series_entries) - len(movie_entries); unchanged > 0 {
+		fmt.Println("skipping", unchanged, "entries unchanged since the last run")
+	}
 
 	fmt.Println("series dirs (", len(series_entries), "): ")
 	for _, series := range series_entries {
@@ -68,159 +127,145 @@ func main() {
 	}
 	fmt.Println()
 
-	var series = Series{}
+	var series = Series{overrides: args.type_overrides}
 	err = series.split_by_type(series_entries)
 	if err != nil {
 		panic(err)
 	}
 
 	fmt.Println("categorized series: ")
-	fmt.Println("named_seasons: ")
-	for _, v := range series.named_seasons {
-		fmt.Println("\t", v)
-	}
-	fmt.Println("single_season_no_movies: ")
-	for _, v := range series.single_season_no_movies {
-		fmt.Println("\t", v)
-	}
-	fmt.Println("single_season_with_movies: ")
-	for _, v := range series.single_season_with_movies {
-		fmt.Println("\t", v)
-	}
-	fmt.Println("multiple_season_no_movies: ")
-	for _, v := range series.multiple_season_no_movies {
-		fmt.Println("\t", v)
-	}
-	fmt.Println("multiple_season_with_movies: ")
-	for _, v := range series.multiple_season_with_movies {
-		fmt.Println("\t", v)
+	for _, kind := range series_kinds() {
+		fmt.Println(string(kind) + ": ")
+		for _, v := range series.entries[kind] {
+			fmt.Println("\t", v)
+		}
 	}
 
-	var movie = Movies{}
+	var movie = Movies{overrides: args.type_overrides}
 	err = movie.split_by_type(movie_entries)
 	if err != nil {
 		panic(err)
 	}
 
 	fmt.Println("categorized movies: ")
-	fmt.Println("standalone: ")
-	for _, v := range movie.standalone {
-		fmt.Println("\t", v)
-	}
-	fmt.Println("movie_set: ")
-	for _, v := range movie.movie_set {
-		fmt.Println("\t", v)
-	}
-
-	fmt.Println("test for named seasons")
-	named_season_options := prompt_additional_options(args.options, "all named seasons", 0)
-	for _, v := range series.named_seasons {
-		info, err := series_rename_prereqs(v, "named_seasons", named_season_options)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Println(info)
-
-		err = info.rename()
-		if err != nil {
-			panic(err)
+	for _, kind := range movie_kinds() {
+		fmt.Println(string(kind) + ": ")
+		for _, v := range movie.entries[kind] {
+			fmt.Println("\t", v)
 		}
 	}
-	fmt.Println()
-
-	fmt.Println("test for single season no movies")
-	ssnm_options := prompt_additional_options(args.options, "all single season with no movies", 0)
-	for _, v := range series.single_season_no_movies {
-		info, err := series_rename_prereqs(v, "single_season_no_movies", ssnm_options)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Println(info)
 
-		err = info.rename()
-		if err != nil {
-			panic(err)
-		}
+	if args.explain {
+		fmt.Println("\nexplain series: ")
+		print_classifications(series.classifications)
+		fmt.Println("explain movies: ")
+		print_classifications(movie.classifications)
+		fmt.Println()
 	}
-	fmt.Println()
 
-	fmt.Println("test for single season with movies")
-	sswm_options := prompt_additional_options(args.options, "all single season with movies", 0)
-	for _, v := range series.single_season_with_movies {
-		info, err := series_rename_prereqs(v, "single_season_with_movies", sswm_options)
+	if args.tui {
+		tui := new_Tui(series, movie, args.options)
+		err = run_tui(tui)
 		if err != nil {
 			panic(err)
 		}
-		fmt.Println(info)
-
-		err = info.rename()
-		if err != nil {
-			panic(err)
+		if tui.apply {
+			err = tui.apply_plan()
+			if err != nil {
+				panic(err)
+			}
 		}
+		return
 	}
-	fmt.Println()
 
-	fmt.Println("test for multiple season no movies")
-	msnm_options := prompt_additional_options(args.options, "all multiple season with no movies", 0)
-	for _, v := range series.multiple_season_no_movies {
-		info, err := series_rename_prereqs(v, "multiple_season_no_movies", msnm_options)
-		if err != nil {
-			panic(err)
+	// entries are planned with up to --jobs at a time, then renamed one by one in order
+	for _, kind := range series_kinds() {
+		fmt.Println("test for", kind.description())
+		options := prompt_additional_options(args.options, "all "+kind.description(), 0)
+		entries := series.entries[kind]
+		infos := make([]SeriesInfo, len(entries))
+		plans := make([][]RenameOp, len(entries))
+		// entries and seasons left as var are prompted for while they are planned. prompts must come in the
+		// order of the entries for piped and recorded answers to go to the right one, so those are planned one by one
+		planning_jobs := jobs
+		if options.has_var() {
+			planning_jobs = 1
 		}
-		fmt.Println(info)
-
-		err = info.rename()
+		err := run_jobs_on(planning_jobs, len(entries), func(i int) error {
+			info, err := series_rename_prereqs(entries[i], kind, options)
+			if err != nil {
+				return err
+			}
+			infos[i] = info
+			plans[i], err = infos[i].plan()
+			return err
+		})
 		if err != nil {
 			panic(err)
 		}
-	}
-	fmt.Println()
-
-	fmt.Println("test for multiple season with movies")
-	mswm_options := prompt_additional_options(args.options, "all multiple season with movies", 0)
-	for _, v := range series.multiple_season_with_movies {
-		info, err := series_rename_prereqs(v, "multiple_season_with_movies", mswm_options)
-		if err != nil {
-			panic(err)
+		for i, v := range entries {
+			fmt.Println(infos[i])
+			err = rename_entry(v, false, plans[i], infos[i].resolved_options())
+			if err != nil {
+				panic(err)
+			}
 		}
-		fmt.Println(info)
-
-		err = info.rename()
+		fmt.Println()
+	}
+
+	for _, kind := range movie_kinds() {
+		fmt.Println("test for", kind.description())
+		entries := movie.entries[kind]
+		infos := make([]MovieInfo, len(entries))
+		plans := make([][]RenameOp, len(entries))
+		err := run_jobs(len(entries), func(i int) error {
+			info, err := movie_rename_prereqs(entries[i], kind)
+			if err != nil {
+				return err
+			}
+			infos[i] = info
+			plans[i], err = infos[i].plan()
+			return err
+		})
 		if err != nil {
 			panic(err)
 		}
-	}
-	fmt.Println()
-
-	fmt.Println("test for standalone")
-	for _, v := range movie.standalone {
-		info, err := movie_rename_prereqs(v, "standalone")
-		if err != nil {
-			panic(err)
+		for i, v := range entries {
+			fmt.Println(infos[i])
+			err = rename_entry(v, true, plans[i], nil)
+			if err != nil {
+				panic(err)
+			}
 		}
-		fmt.Println(info)
+		fmt.Println()
+	}
 
-		err = info.rename()
+	if library != nil {
+		err = library.prune()
 		if err != nil {
 			panic(err)
 		}
 	}
-	fmt.Println()
+}
 
-	fmt.Println("test for movie set")
-	for _, v := range movie.movie_set {
-		info, err := movie_rename_prereqs(v, "movie_set")
-		if err != nil {
-			panic(err)
+// rename_entry applies the planned renames of an entry, links them into the --link library, or moves them to --dest,
+// and remembers it in the state file, with the options it was renamed with, if every file got its new name
+func rename_entry(entry string, is_movie bool, ops []RenameOp, options []AdditionalOptions) error {
+	if library != nil {
+		ops = library.link_ops(entry, is_movie, ops)
+		if err := library.link_entry(entry, ops); err != nil {
+			return err
 		}
-		fmt.Println(info)
-
-		err = info.rename()
-		if err != nil {
-			panic(err)
+	} else if dest_root != "" {
+		ops = dest_ops(entry, is_movie, ops)
+		if err := apply_renames(ops); err != nil {
+			return err
 		}
+		remove_moved_dirs(entry, ops)
+	} else if err := apply_renames(ops); err != nil {
+		return err
 	}
-	fmt.Println()
+	return state.record(entry, ops, options)
 }
 
 // fetch_entries retrieves the series and movie entries from the given root, series, and movie directories.
@@ -235,43 +280,90 @@ func fetch_entries(root_dirs []string, series_dirs []string, movie_dirs []string
 		return nil, nil, fmt.Errorf("passed no root, series, or movie directories")
 	}
 
-	entries := map[string][]string{
-		"movies":  make([]string, 0),
-		"series": make([]string, 0),
+	// subroots are listed first so they can be scanned at the same time
+	type subroot struct {
+		dir   string
+		media string
 	}
+	subroots := make([]subroot, 0)
 	for _, root := range root_dirs {
 		separated, err := separate_roots(root)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		for key, roots := range separated {
-			for _, dir := range roots {
-				subdirs, err := fetch_subdirs(dir)
-				if err != nil {
-					return nil, nil, err
-				}
-				entries[key] = append(entries[key], subdirs...)
+		for _, key := range []string{"series", "movies"} {
+			for _, dir := range separated[key] {
+				subroots = append(subroots, subroot{dir, key})
 			}
 		}
 	}
-
 	for _, v := range series_dirs {
-		subdirs, err := fetch_subdirs(v)
-		if err != nil {
-			return nil, nil, err
-		}
-		entries["series"] = append(entries["series"], subdirs...)
+		subroots = append(subroots, subroot{v, "series"})
 	}
 	for _, v := range movie_dirs {
-		subdirs, err := fetch_subdirs(v)
-		if err != nil {
-			return nil, nil, err
-		}
-		entries["movies"] = append(entries["movies"], subdirs...)
+		subroots = append(subroots, subroot{v, "movies"})
 	}
 
-	return entries["series"], entries["movies"], nil
+	found := make([][]string, len(subroots))
+	err := run_jobs(len(subroots), func(i int) error {
+		subdirs, err := fetch_subdirs(subroots[i].dir, subroots[i].media)
+		found[i] = subdirs
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := map[string][]string{
+		"movies":  make([]string, 0),
+		"series": make([]string, 0),
+	}
+	for i, subdirs := range found {
+		entries[subroots[i].media] = append(entries[subroots[i].media], subdirs...)
+	}
+
+	series, movies := filter_entries(entries["series"]), filter_entries(entries["movies"])
+	if len(series) == 0 && len(movies) == 0 {
+		return nil, nil, fmt.Errorf("no entries left after --include and --exclude")
+	}
+	return series, movies, nil
+}
+
+// how deep under a root subroots are looked for, and how deep under a subroot entries are looked for.
+// directories in between must be categories (see is_category). the two are counted separately, so entries can be
+// up to 2 * discovery_depth directories under a root
+var discovery_depth = 1
+
+// names of subroot directories (lowercase) and whether they hold series or movies. --alias adds more
+var builtin_subroot_names = map[string]string{
+	"movies":  "movies",
+	"movie":   "movies",
+	"series":  "series",
+	"shows":   "series",
+	"show":    "series",
+	"tv show": "series",
+	"tv":      "series",
+}
+
+var subroot_names = builtin_subroot_names
+
+// names of category directories (lowercase) like "anime" in series/anime/<entry>. set with --category
+var category_names = map[string]bool{}
+
+// set_discovery applies --depth, --alias, and --category
+func set_discovery(depth int, aliases map[string]string, categories []string) {
+	discovery_depth = depth
+	subroot_names = make(map[string]string)
+	for name, media := range builtin_subroot_names {
+		subroot_names[name] = media
+	}
+	for name, media := range aliases {
+		subroot_names[strings.ToLower(name)] = media
+	}
+	category_names = make(map[string]bool)
+	for _, name := range categories {
+		category_names[strings.ToLower(name)] = true
+	}
 }
 
 func separate_roots(root string) (map[string][]string, error) {
@@ -279,35 +371,23 @@ func separate_roots(root string) (map[string][]string, error) {
 		"movies": {},
 		"series": {},
 	}
-	valid_movie_path_names := map[string]bool{
-		"movies": true,
-		"movie":  true,
-	}
-	valid_series_path_names := map[string]bool{
-		"series":  true,
-		"shows":   true,
-		"show":    true,
-		"tv show": true,
-		"tv":      true,
-	}
 
 	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if d.IsDir() {
-			// get only directories of depth 1 (directly under root)
-			if path != root && filepath.Dir(path) == root {
-				dir_name := strings.ToLower(filepath.Base(path))
-				if valid_movie_path_names[dir_name] {
-					root_dirs["movies"] = append(root_dirs["movies"], path)
-					return filepath.SkipDir
-
-				} else if valid_series_path_names[dir_name] {
-					root_dirs["series"] = append(root_dirs["series"], path)
-					return filepath.SkipDir
-				}
+		if d.IsDir() && path != root {
+			if strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			// get only directories up to discovery_depth under root
+			if media, ok := subroot_names[strings.ToLower(d.Name())]; ok {
+				root_dirs[media] = append(root_dirs[media], path)
+				return filepath.SkipDir
+			}
+			if dir_depth(root, path) >= discovery_depth {
+				return filepath.SkipDir
 			}
 		}
 		return nil
@@ -324,20 +404,32 @@ func separate_roots(root string) (map[string][]string, error) {
 	return root_dirs, nil
 }
 
-func fetch_subdirs(dir string) ([]string, error) {
+// fetch_subdirs returns the entries under a series or movies subroot. media is "series" or "movies".
+// categories are looked into instead of being entries until discovery_depth is reached
+func fetch_subdirs(dir string, media string) ([]string, error) {
 	entries := []string{}
 	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if d.IsDir() {
-			// get only directories of depth 1 (directly under series dir) and does not start with a '.'
-			if path != dir && filepath.Dir(path) == dir && !strings.HasPrefix(filepath.Base(path), ".") {
-				entries = append(entries, path)
-				return filepath.SkipDir
+		if !d.IsDir() || path == dir {
+			return nil
+		}
+		// hidden directories are never entries
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if dir_depth(dir, path) < discovery_depth {
+			is_category, err := is_category(path, media)
+			if err != nil {
+				return err
+			}
+			if is_category {
+				return nil
 			}
 		}
-		return nil
+		entries = append(entries, path)
+		return filepath.SkipDir
 	})
 
 	if err != nil {
@@ -348,4 +440,40 @@ func fetch_subdirs(dir string) ([]string, error) {
 	}
 
 	return entries, nil
-}
\ No newline at end of file
+}
+
+// is_category reports whether a directory under a subroot groups entries instead of being one.
+// directories named with --category are categories. series directories are also categories if they match
+// no series type and have no media files directly under them, unless they have a type marker file
+func is_category(path string, media string) (bool, error) {
+	if category_names[strings.ToLower(filepath.Base(path))] {
+		return true, nil
+	}
+	if media != "series" {
+		return false, nil
+	}
+	if _, err := os.Stat(filepath.Join(path, type_marker_file)); err == nil {
+		return false, nil
+	}
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+	for _, file := range files {
+		if !file.IsDir() && is_media_file(file.Name()) {
+			return false, nil
+		}
+	}
+	rule, _, err := classify(path, series_rules, series_extras_pattern)
+	return rule == nil, err
+}
+
+// dir_depth is how many directories deep path is under parent
+func dir_depth(parent string, path string) int {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
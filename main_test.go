@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -351,6 +353,35 @@ func Test_naming_scheme_validation(t *testing.T) {
 	}
 }
 
+func Test_prompt_additional_options(t *testing.T) {
+	previous := prompter
+	defer func() { prompter = previous }()
+
+	// a naming scheme given with --naming-scheme is kept without asking for one
+	prompter = new_Prompter(strings.NewReader("default\n"))
+	options := prompt_additional_options(AdditionalOptions{
+		keep_ep_nums:    some[bool](false),
+		starting_ep_num: some[int](1),
+		has_season_0:    some[bool](false),
+		naming_scheme:   some[string]("S<season_num>E<episode_num>"),
+	}, "Show", 1)
+	if ns, _ := options.naming_scheme.get(); ns != "S<season_num>E<episode_num>" {
+		t.Errorf("expected naming scheme 'S<season_num>E<episode_num>' to be kept; got '%s'", ns)
+	}
+
+	// a naming scheme left as var is asked for
+	prompter = new_Prompter(strings.NewReader("<title> <episode_num>\n"))
+	options = prompt_additional_options(AdditionalOptions{
+		keep_ep_nums:    some[bool](false),
+		starting_ep_num: some[int](1),
+		has_season_0:    some[bool](false),
+		naming_scheme:   none[string](),
+	}, "Show", 1)
+	if ns, _ := options.naming_scheme.get(); ns != "<title> <episode_num>" {
+		t.Errorf("expected naming scheme '<title> <episode_num>' to be answered; got '%s'", ns)
+	}
+}
+
 func Test_split_regex_by_pipe(t *testing.T) {
 	t.Log("------------expects errors------------")
 	parts := split_regex_by_pipe(``)
@@ -465,4 +496,1420 @@ func Test_generate_new_name(t *testing.T) {
 		}
 	}
 
-}
\ No newline at end of file
+}
+func Test_parse_release_name(t *testing.T) {
+	t.Log("------------expects success------------")
+	name := "Show.Name.2019.S01E02-E03.1080p.WEB-DL.DDP5.1.x264-GROUP.mkv"
+	info := parse_release_name(name)
+	if info.title != "Show Name" || info.year != 2019 || info.season != 1 {
+		t.Errorf("expected title 'Show Name', year 2019, season 1; got '%s', %d, %d", info.title, info.year, info.season)
+	} else if len(info.episodes) != 2 || info.episodes[0] != 2 || info.episodes[1] != 3 {
+		t.Errorf("expected episodes [2 3]; got %v", info.episodes)
+	} else if info.resolution != "1080p" || info.source != "WEB-DL" || info.codec != "AVC" || info.audio != "EAC3 5.1" || info.group != "GROUP" {
+		t.Errorf("expected 1080p WEB-DL AVC 'EAC3 5.1' GROUP; got %s %s %s '%s' %s", info.resolution, info.source, info.codec, info.audio, info.group)
+	} else {
+		t.Log(name, "\n\t", info)
+	}
+
+	name = "[SubsPlease] Show Name - 05 (1080p) [ABCD1234].mkv"
+	info = parse_release_name(name)
+	if info.title != "Show Name" || info.group != "SubsPlease" || info.season != -1 || len(info.episodes) != 1 || info.episodes[0] != 5 {
+		t.Errorf("expected title 'Show Name', group 'SubsPlease', no season, episode 5; got %v", info)
+	} else {
+		t.Log(name, "\n\t", info)
+	}
+
+	name = "1917.2019.2160p.UHD.BluRay.x265.TrueHD.7.1.Atmos-SWTYRANT.mkv"
+	info = parse_release_name(name)
+	if info.title != "1917" || info.year != 2019 || info.resolution != "2160p" || info.codec != "HEVC" {
+		t.Errorf("expected title '1917', year 2019, 2160p, HEVC; got %v", info)
+	} else {
+		t.Log(name, "\n\t", info)
+	}
+
+	name = "The.Office.US.S02E01.PROPER.720p.HDTV.x264-FOO"
+	info = parse_release_name(name)
+	if !info.proper || info.repack || info.tokens()["release_flags"] != "PROPER" {
+		t.Errorf("expected PROPER flag only; got %v", info)
+	} else {
+		t.Log(name, "\n\t", info)
+	}
+
+	for name, episode_title := range map[string]string{
+		"Show.S01E04.The.Big.Day.1080p.WEB-DL.x264-GRP.mkv":  "The Big Day",
+		"Show S01E04 - Pilot Part 2.mkv":                     "Pilot Part 2",
+		"Show.1x04.Pilot-GRP.mkv":                            "Pilot",
+		"[SubsPlease] Show Name - 05 (1080p) [ABCD1234].mkv": "",
+	} {
+		if info := parse_release_name(name); info.episode_title != episode_title {
+			t.Errorf("expected episode title '%s' for %s; got '%s'", episode_title, name, info.episode_title)
+		}
+	}
+
+	for name, part := range map[string]int{
+		"Movie.2004.CD1.XviD-GRP.avi":           1,
+		"Movie.2004.Part2.1080p.mkv":            2,
+		"Movie.2004.PAL.DVD9.MPEG2-GRP.mkv":     0,
+		"Movie.2004.DVD5.Extended.x264-GRP.mkv": 0,
+		"Kill Bill Part 2 (2004)":               2,
+		"Movie - Part 2":                        2,
+		"Deathly Hallows Part 1 (2010)":         1,
+		"Movie.2004.Pt.3.1080p.mkv":             3,
+	} {
+		if info := parse_release_name(name); info.part != part {
+			t.Errorf("expected part %d for %s; got %d", part, name, info.part)
+		}
+	}
+	for name, expected := range map[string][2]string{
+		"The.Extended.Family.2020.1080p.WEB-DL.mkv": {"The Extended Family", ""},
+		"Uncut.Gems.2019.1080p.mkv":                 {"Uncut Gems", ""},
+		"Movie.B.1999.Directors.Cut.1080p.mkv":      {"Movie B", "Director's Cut"},
+		"Movie - Extended.mkv":                      {"Movie", "Extended"},
+		"Movie [Unrated].mkv":                       {"Movie", "Unrated"},
+	} {
+		if info := parse_release_name(name); info.title != expected[0] || info.edition != expected[1] {
+			t.Errorf("expected title '%s' and edition '%s' for %s; got '%s' and '%s'", expected[0], expected[1], name, info.title, info.edition)
+		}
+	}
+}
+
+// ebml element with a 1 byte size (data must be < 127 bytes)
+func test_ebml(id []byte, data ...[]byte) []byte {
+	var body []byte
+	for _, d := range data {
+		body = append(body, d...)
+	}
+	return append(append(id, byte(0x80|len(body))), body...)
+}
+
+// mp4 box with a 32 bit size
+func test_mp4_box(kind string, data ...[]byte) []byte {
+	var body []byte
+	for _, d := range data {
+		body = append(body, d...)
+	}
+	size := len(body) + 8
+	return append([]byte{byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size), kind[0], kind[1], kind[2], kind[3]}, body...)
+}
+
+func Test_probe_media_file(t *testing.T) {
+	dir := t.TempDir()
+	t.Log("------------expects errors------------")
+	not_media := filepath.Join(dir, "not_media.mkv")
+	if err := os.WriteFile(not_media, []byte("definitely not a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := probe_media_file(not_media); err == nil {
+		t.Errorf("expected error 'unsupported container'")
+	} else {
+		t.Log(not_media, "\n\t", err, "\n")
+	}
+
+	t.Log("------------expects release tokens when the file can't be probed------------")
+	release_path := filepath.Join(dir, "Show.S01E01.720p.WEB-DL.x265.AAC-GRP.mkv")
+	if err := os.WriteFile(release_path, []byte("definitely not a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scheme, err := compile_naming_scheme("S<season_num>E<episode_num> <video_res> <video_codec> <audio>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := scheme.render(SchemeContext{season_pad: 2, season_num: 1, ep_pad: 2, ep_num: 1, abs_path: release_path})
+	if err != nil || name != "S01E01 720p HEVC AAC" {
+		t.Errorf("expected 'S01E01 720p HEVC AAC'; got '%s' (%v)", name, err)
+	} else {
+		t.Log(release_path, "\n\t", name, "\n")
+	}
+
+	t.Log("------------expects success------------")
+	// 1920x1080 HEVC HDR10 + 6 channel EAC3, 1 hour long
+	mkv := test_ebml([]byte{0x1A, 0x45, 0xDF, 0xA3}, test_ebml([]byte{0x42, 0x82}, []byte("matroska")))
+	mkv = append(mkv, test_ebml([]byte{0x18, 0x53, 0x80, 0x67},
+		test_ebml([]byte{0x15, 0x49, 0xA9, 0x66},
+			test_ebml([]byte{0x2A, 0xD7, 0xB1}, []byte{0x0F, 0x42, 0x40}),
+			test_ebml([]byte{0x44, 0x89}, []byte{0x4A, 0x5B, 0xBA, 0x00})), // 3600000.0 ms as float32
+		test_ebml([]byte{0x16, 0x54, 0xAE, 0x6B},
+			test_ebml([]byte{0xAE},
+				test_ebml([]byte{0x83}, []byte{1}),
+				test_ebml([]byte{0x86}, []byte("V_MPEGH/ISO/HEVC")),
+				test_ebml([]byte{0xE0},
+					test_ebml([]byte{0xB0}, []byte{0x07, 0x80}),
+					test_ebml([]byte{0xBA}, []byte{0x04, 0x38}),
+					test_ebml([]byte{0x55, 0xB0}, test_ebml([]byte{0x55, 0xBA}, []byte{16})))),
+			test_ebml([]byte{0xAE},
+				test_ebml([]byte{0x83}, []byte{2}),
+				test_ebml([]byte{0x86}, []byte("A_EAC3")),
+				test_ebml([]byte{0xE1}, test_ebml([]byte{0x9F}, []byte{6})))))...)
+	mkv_path := filepath.Join(dir, "episode.mkv")
+	if err := os.WriteFile(mkv_path, mkv, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := probe_media_file(mkv_path)
+	tokens := info.tokens()
+	if err != nil {
+		t.Error("expected no error; got", err)
+	} else if tokens["video_res"] != "1080p" || tokens["video_codec"] != "HEVC" || tokens["hdr"] != "HDR10" || tokens["audio"] != "EAC3 5.1" || tokens["duration"] != "1h00m" {
+		t.Errorf("expected 1080p HEVC HDR10 'EAC3 5.1' 1h00m; got %v", tokens)
+	} else {
+		t.Log(mkv_path, "\n\t", tokens)
+	}
+
+	// 1280x720 AVC + stereo AAC, 90 seconds long
+	visual_entry := make([]byte, 78)
+	visual_entry[24], visual_entry[25], visual_entry[26], visual_entry[27] = 0x05, 0x00, 0x02, 0xD0
+	audio_entry := make([]byte, 28)
+	audio_entry[17] = 2
+	mvhd := make([]byte, 100)
+	// timescale 100, duration 9000 (90s)
+	mvhd[15], mvhd[18], mvhd[19] = 100, 0x23, 0x28
+	trak := func(handler string, entry []byte) []byte {
+		hdlr := append(make([]byte, 8), handler...)
+		return test_mp4_box("trak", test_mp4_box("mdia",
+			test_mp4_box("hdlr", hdlr, make([]byte, 12)),
+			test_mp4_box("minf", test_mp4_box("stbl", test_mp4_box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, entry)))))
+	}
+	mp4 := append(test_mp4_box("ftyp", []byte("isom")), test_mp4_box("mdat", make([]byte, 64))...)
+	mp4 = append(mp4, test_mp4_box("moov",
+		test_mp4_box("mvhd", mvhd),
+		trak("vide", test_mp4_box("avc1", visual_entry)),
+		trak("soun", test_mp4_box("mp4a", audio_entry)))...)
+	mp4_path := filepath.Join(dir, "episode.mp4")
+	if err := os.WriteFile(mp4_path, mp4, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err = probe_media_file(mp4_path)
+	tokens = info.tokens()
+	if err != nil {
+		t.Error("expected no error; got", err)
+	} else if tokens["video_res"] != "720p" || tokens["video_codec"] != "AVC" || tokens["hdr"] != "" || tokens["audio"] != "AAC 2.0" || tokens["duration"] != "2m" {
+		t.Errorf("expected 720p AVC 'AAC 2.0' 2m; got %v", tokens)
+	} else {
+		t.Log(mp4_path, "\n\t", tokens)
+	}
+}
+
+func Test_tui(t *testing.T) {
+	dir := t.TempDir()
+	show := filepath.Join(dir, "Show")
+	season := filepath.Join(show, "Season 1")
+	if err := os.MkdirAll(season, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.mkv", "b.mkv"} {
+		if err := os.WriteFile(filepath.Join(season, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tui := new_Tui(Series{entries: map[SeriesKind][]string{kind_multiple_season_no_movies: {show}}}, Movies{}, new_Args().options)
+	if len(tui.entries) != 1 || len(tui.entries[0].ops) != 2 {
+		t.Fatalf("expected 1 entry with 2 planned renames; got %d entries", len(tui.entries))
+	}
+	if filepath.Base(tui.entries[0].ops[0].new) != "S01E01 Show.mkv" {
+		t.Errorf("expected 'S01E01 Show.mkv'; got '%s'", filepath.Base(tui.entries[0].ops[0].new))
+	}
+
+	// open the entry, exclude the second file, then change the naming scheme of season 1
+	keys := []string{"enter", "down", "x", "up", "n"}
+	keys = append(keys, parse_keys([]byte("\x7f\x7f\x7f\x7f\x7f\x7f\x7fE<episode_num>\r"))...)
+	for _, key := range keys {
+		tui.handle_key(key)
+	}
+	if tui.input != nil {
+		t.Fatalf("expected naming scheme input to be done; status: %s", tui.status)
+	}
+	if filepath.Base(tui.entries[0].ops[0].new) != "E01.mkv" || !tui.entries[0].ops[1].skip {
+		t.Errorf("expected 'E01.mkv' and second file excluded; got %v", tui.entries[0].ops)
+	} else {
+		t.Log(tui.render(80, 24))
+	}
+
+	tui.handle_key("a")
+	if !tui.done || !tui.apply {
+		t.Fatal("expected 'a' to finish the ui and apply the plan")
+	}
+	if err := tui.apply_plan(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(season, "E01.mkv")); err != nil {
+		t.Error("expected a.mkv to be renamed to E01.mkv")
+	}
+	if _, err := os.Stat(filepath.Join(season, "b.mkv")); err != nil {
+		t.Error("expected excluded b.mkv to be left alone")
+	}
+}
+
+func Test_answers_file(t *testing.T) {
+	dir := t.TempDir()
+	answers_path := filepath.Join(dir, "answers.json")
+	record_path := filepath.Join(dir, "recorded.json")
+	entry := filepath.Join(dir, "Show")
+	err := os.WriteFile(answers_path, []byte(`[
+		{"level": "entry", "path": "`+filepath.ToSlash(entry)+`", "option": "keep_ep_nums", "answer": "y"},
+		{"level": "entry", "path": "`+filepath.ToSlash(entry)+`", "option": "starting_ep_num", "answer": "not a number"}
+	]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := prompter
+	defer func() { prompter = previous }()
+
+	t.Log("------------expects errors------------")
+	prompter = new_Prompter(strings.NewReader(""))
+	bad_path := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad_path, []byte(`[{"level": "episode", "path": "x", "option": "keep_ep_nums", "answer": "y"}]`), 0644)
+	if err := prompter.load_answers(bad_path); err == nil {
+		t.Errorf("expected error 'invalid level episode'")
+	} else {
+		t.Log(bad_path, "\n\t", err, "\n")
+	}
+
+	t.Log("------------expects success------------")
+	// invalid starting episode number in the file falls back to stdin
+	prompter = new_Prompter(strings.NewReader("13\nS<season_num>E<episode_num>\n"))
+	if err := prompter.load_answers(answers_path); err != nil {
+		t.Fatal(err)
+	}
+	prompter.record_to(record_path)
+	options := prompt_additional_options(AdditionalOptions{
+		keep_ep_nums:    none[bool](),
+		starting_ep_num: none[int](),
+		has_season_0:    some[bool](false),
+		naming_scheme:   none[string](),
+	}, entry, 1)
+
+	ken, _ := options.keep_ep_nums.get()
+	sen, _ := options.starting_ep_num.get()
+	ns, _ := options.naming_scheme.get()
+	if !ken || sen != 13 || ns != "S<season_num>E<episode_num>" {
+		t.Errorf("expected keep_ep_nums true, starting_ep_num 13, naming scheme 'S<season_num>E<episode_num>'; got %t, %d, '%s'", ken, sen, ns)
+	}
+
+	recorded, err := os.ReadFile(record_path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(recorded), `"level": "entry"`) != 3 {
+		t.Errorf("expected 3 recorded answers; got %s", recorded)
+	} else {
+		t.Log(string(recorded))
+	}
+}
+
+func Test_split_by_type(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"Show/Season 1/a.mkv", "Show/Season 2/a.mkv", "Show/Movie/a.mkv", "Gundam/Part 1/a.mkv", "Single/a.mkv"} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := []string{filepath.Join(dir, "Show"), filepath.Join(dir, "Gundam"), filepath.Join(dir, "Single")}
+
+	t.Log("------------expects errors------------")
+	os.WriteFile(filepath.Join(dir, "Gundam", type_marker_file), []byte("standalone\n"), 0644)
+	series := Series{}
+	if err := series.split_by_type(entries); err == nil {
+		t.Errorf("expected error 'invalid type standalone in marker file'")
+	} else {
+		t.Log(err)
+	}
+
+	t.Log("------------expects success------------")
+	os.WriteFile(filepath.Join(dir, "Gundam", type_marker_file), []byte("named_seasons\n"), 0644)
+	series = Series{overrides: []TypeOverride{{pattern: "Sing*", kind: "multiple_season_no_movies"}}}
+	if err := series.split_by_type(entries); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"Show":   "multiple_season_with_movies",
+		"Gundam": "named_seasons",
+		"Single": "multiple_season_no_movies",
+	}
+	for _, c := range series.classifications {
+		if expected[filepath.Base(c.path)] != c.kind {
+			t.Errorf("expected %s to be %s; got %s (%s)", filepath.Base(c.path), expected[filepath.Base(c.path)], c.kind, c.reason)
+		} else {
+			t.Log(filepath.Base(c.path), c.kind, "\n\t", c.rule, ":", c.reason)
+		}
+	}
+}
+
+func Test_explain_entry(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"Show/Season 1/a 01.mkv", "Show/Season 1/a 02.mkv", "Show/Season 2/b 01.mkv", "Show/Extras/c.mkv"} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Log("------------expects errors------------")
+	if err := explain_entry([]string{filepath.Join(dir, "Missing")}); err == nil {
+		t.Errorf("expected error 'entry is not a directory'")
+	} else {
+		t.Log(err)
+	}
+
+	t.Log("------------expects success------------")
+	var out strings.Builder
+	trace_output = &out
+	seasons, _, _, err := fetch_series_content(filepath.Join(dir, "Show"), kind_multiple_season_no_movies, true)
+	trace_output = nil
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seasons[0] != "Extras" || seasons[1] != "Season 1" || seasons[2] != "Season 2" {
+		t.Errorf("unexpected seasons %v", seasons)
+	}
+	for _, expected := range []string{"'Extras': season 0, matches extras pattern", "'Season 2': season 2, is a season folder"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected trace to contain %q; got\n%s", expected, out.String())
+		}
+	}
+	t.Log("\n" + out.String())
+
+	if err := explain_entry([]string{filepath.Join(dir, "Show"), "-s0", "all", "yes"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Show", "Season 1", "a 01.mkv")); err != nil {
+		t.Errorf("explain should not rename anything: %s", err)
+	}
+}
+
+func Test_type_rules(t *testing.T) {
+	defer func(series []*TypeRule, movies []*TypeRule) {
+		series_rules, movie_rules = series, movies
+	}(series_rules, movie_rules)
+
+	dir := t.TempDir()
+	for _, path := range []string{"Box/Disc 1/a.mkv", "Box/Disc 2/a.mkv", "Box/Extras/b.mkv", "Show/Season 1/a.mkv", "Film/Film.mkv"} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write_rules := func(rules string) string {
+		path := filepath.Join(dir, "rules.json")
+		if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Log("------------expects errors------------")
+	invalid_rules := []string{
+		`{"series": [{"name": "discs", "subdir_pattern": "disc\\d+", "season_pattern": "disc\\d+"}]}`,
+		`{"series": [{"name": "discs", "subdir_pattern": "(disc"}]}`,
+		`{"series": [{"name": "discs", "subdir_pattern": "disc", "title": "disc"}]}`,
+		`{"series": [{"name": "everything"}]}`,
+		`{"series": [{"name": "standalone", "min_media_files": 1}]}`,
+		`{"series": [{"name": "discs", "min_media_files": 1}, {"name": "discs", "min_media_files": 2}]}`,
+		`{"series": {"name": "discs"}}`,
+	}
+	for _, rules := range invalid_rules {
+		if err := load_rules(write_rules(rules)); err == nil {
+			t.Errorf("expected error for rules %s", rules)
+		} else {
+			t.Log(err)
+		}
+	}
+	if _, err := SeriesKind("discs").rule(); err == nil {
+		t.Errorf("expected invalid rules files to not change the registry")
+	}
+
+	t.Log("------------expects success------------")
+	err := load_rules(write_rules(`{
+		"series": [{"name": "discs", "subdir_pattern": "^(?i)disc\\s*\\d+", "min_subdirs": 2, "season_pattern": "^(?i)disc\\s*(\\d+)", "has_movies": true}],
+		"movies": [{"name": "standalone", "min_media_files": 1, "max_media_files": 1}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series_rules[0].Name != "discs" || find_rule(movie_rules, "standalone").source == "built-in" || len(movie_rules) != 2 {
+		t.Errorf("expected discs to be checked first and standalone to be replaced; got %v %v", rule_names(series_rules), rule_names(movie_rules))
+	}
+
+	series := Series{}
+	if err := series.split_by_type([]string{filepath.Join(dir, "Box"), filepath.Join(dir, "Show")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(series.entries["discs"]) != 1 || len(series.entries[kind_multiple_season_no_movies]) != 1 {
+		t.Errorf("expected Box to be discs and Show to be multiple_season_no_movies; got %v", series.classifications)
+	}
+	movies := Movies{}
+	if err := movies.split_by_type([]string{filepath.Join(dir, "Film")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(movies.entries[kind_standalone]) != 1 {
+		t.Errorf("expected Film to be standalone; got %v", movies.classifications)
+	}
+
+	info, err := series_rename_prereqs(filepath.Join(dir, "Box"), "discs", new_Args().options.with_defaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.seasons) != 2 || info.seasons[2] != "Disc 2" || len(info.movies) != 0 {
+		t.Errorf("expected 2 discs as seasons and Extras ignored; got %v %v", info.seasons, info.movies)
+	}
+	ops, err := info.plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range ops {
+		t.Log(filepath.Base(op.old), "->", filepath.Base(op.new))
+		if filepath.Base(op.new) != fmt.Sprintf("S%02dE01 Box.mkv", op.season) {
+			t.Errorf("unexpected new name %s", op.new)
+		}
+	}
+}
+
+func Test_season_folder_number(t *testing.T) {
+	defer func(words []string) {
+		season_words = words
+		season_word_pattern = compile_season_word_pattern(words)
+	}(season_words)
+
+	tests := map[string]int{
+		"Season 1":          1,
+		"season 12 - Title": 12,
+		"Season01":          1,
+		"Season.02":         2,
+		"S01":               1,
+		"s3 [1080p]":        3,
+		"Series 2":          2,
+		"Staffel 3":         3,
+		"Saison 4":          4,
+		"Temporada 5":       5,
+		"Season II":         2,
+		"Season IV":         4,
+		"season xiv":        14,
+		"Series XC":         90,
+	}
+	for name, expected := range tests {
+		num, ok := season_folder_number(name)
+		if !ok || num != expected {
+			t.Errorf("expected '%s' to be season %d; got %d (%t)", name, expected, num, ok)
+		}
+	}
+
+	not_seasons := []string{"Specials", "Extras", "The Movie", "Season Finale", "Season IIII", "Series Civil War", "S01E01", "Seasons", "1. Named"}
+	for _, name := range not_seasons {
+		if num, ok := season_folder_number(name); ok {
+			t.Errorf("expected '%s' to not be a season folder; got season %d", name, num)
+		}
+	}
+
+	if err := add_season_words([]string{"Kausi2"}, "test"); err == nil {
+		t.Errorf("expected error 'season word must not contain digits'")
+	}
+	if err := add_season_words([]string{"Sezonul"}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if num, ok := season_folder_number("Sezonul 7"); !ok || num != 7 {
+		t.Errorf("expected 'Sezonul 7' to be season 7; got %d (%t)", num, ok)
+	}
+}
+
+func Test_specials(t *testing.T) {
+	defer func() {
+		set_specials_order(nil)
+		specials_range = 0
+	}()
+
+	dir := t.TempDir()
+	for _, path := range []string{"Show/Season 1/a.mkv", "Show/Specials/s1.mkv", "Show/Specials/s2.mkv", "Show/OVA/o1.mkv", "Show/Extras/e1.mkv"} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan_names := func() []string {
+		options := new_Args().options.with_defaults()
+		options.has_season_0 = some[bool](true)
+		options.naming_scheme = some[string]("S<season_num>E<episode_num> <special_kind>")
+		info, err := series_rename_prereqs(filepath.Join(dir, "Show"), kind_multiple_season_no_movies, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := info.plan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(ops))
+		for _, op := range ops {
+			names = append(names, filepath.Base(op.old)+" -> "+filepath.Base(op.new))
+		}
+		return names
+	}
+	check := func(expected []string) {
+		got := plan_names()
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	t.Log("------------expects errors------------")
+	for _, order := range [][]string{{"bonus"}, {"ova", "ova"}} {
+		if err := set_specials_order(order); err == nil {
+			t.Errorf("expected error for specials order %v", order)
+		} else {
+			t.Log(err)
+		}
+	}
+
+	t.Log("------------expects success------------")
+	set_specials_order(nil)
+	check([]string{
+		"s1.mkv -> S00E01 Special.mkv",
+		"s2.mkv -> S00E02 Special.mkv",
+		"o1.mkv -> S00E03 OVA.mkv",
+		"e1.mkv -> S00E04 Extra.mkv",
+		"a.mkv -> S01E01 .mkv",
+	})
+
+	if err := set_specials_order([]string{"extras", "ova"}); err != nil {
+		t.Fatal(err)
+	}
+	specials_range = 10
+	check([]string{
+		"e1.mkv -> S00E01 Extra.mkv",
+		"o1.mkv -> S00E11 OVA.mkv",
+		"s1.mkv -> S00E21 Special.mkv",
+		"s2.mkv -> S00E22 Special.mkv",
+		"a.mkv -> S01E01 .mkv",
+	})
+
+	t.Log("------------expects errors------------")
+	overflow_dir := t.TempDir()
+	for _, path := range []string{"Show/Season 1/a.mkv", "Show/Specials/Special E12.mkv"} {
+		path = filepath.Join(overflow_dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, keep_ep_nums := range []bool{false, true} {
+		specials_range = 1
+		show := filepath.Join(dir, "Show")
+		if keep_ep_nums {
+			specials_range = 10
+			show = filepath.Join(overflow_dir, "Show")
+		}
+		options := new_Args().options.with_defaults()
+		options.has_season_0 = some[bool](true)
+		options.keep_ep_nums = some[bool](keep_ep_nums)
+		info, err := series_rename_prereqs(show, kind_multiple_season_no_movies, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := info.plan(); err == nil {
+			t.Errorf("expected error 'more than %d special episodes' with keep_ep_nums %t", specials_range, keep_ep_nums)
+		} else {
+			t.Log(err)
+		}
+	}
+}
+
+func Test_fetch_entries(t *testing.T) {
+	defer set_discovery(1, nil, nil)
+
+	dir := t.TempDir()
+	for _, path := range []string{
+		"Media/TV/Anime/Frieren/Season 1/a.mkv",
+		"Media/TV/Western/Severance/Season 1/a.mkv",
+		"Media/TV/Single/a.mkv",
+		"Media/TV/.hidden/Show/a.mkv",
+		"Media/Films/Marvel/Iron Man/x.mkv",
+		"Media/Films/Heat/h.mkv",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.Join(dir, "Media")
+	relative := func(paths []string) string {
+		rel := make([]string, 0, len(paths))
+		for _, path := range paths {
+			r, _ := filepath.Rel(root, path)
+			rel = append(rel, filepath.ToSlash(r))
+		}
+		return strings.Join(rel, ", ")
+	}
+
+	t.Log("------------expects errors------------")
+	if _, _, err := fetch_entries([]string{dir}, nil, nil); err == nil {
+		t.Errorf("expected error 'no movie and series directory found' for subroots 2 directories deep at depth 1")
+	} else {
+		t.Log(err)
+	}
+
+	t.Log("------------expects success------------")
+	series, movies, err := fetch_entries([]string{root}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relative(series) != "TV/Anime, TV/Single, TV/Western" || len(movies) != 0 {
+		t.Errorf("expected categories to be entries at depth 1; got series: %s; movies: %s", relative(series), relative(movies))
+	}
+
+	set_discovery(2, map[string]string{"Films": "movies"}, []string{"marvel"})
+	series, movies, err = fetch_entries([]string{dir}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = dir
+	if relative(series) != "Media/TV/Anime/Frieren, Media/TV/Single, Media/TV/Western/Severance" ||
+		relative(movies) != "Media/Films/Heat, Media/Films/Marvel/Iron Man" {
+		t.Errorf("unexpected entries at depth 2; got series: %s; movies: %s", relative(series), relative(movies))
+	}
+}
+
+func Test_filters(t *testing.T) {
+	defer set_filters(nil, nil)
+
+	t.Log("------------expects errors------------")
+	for _, pattern := range []string{"[", "re:("} {
+		if _, err := new_filter(pattern, false); err == nil {
+			t.Errorf("expected error for invalid pattern '%s'", pattern)
+		} else {
+			t.Log(err)
+		}
+	}
+
+	t.Log("------------expects success------------")
+	include, _ := new_filter("Frieren*", false)
+	include_regex, _ := new_filter("re:(?i)severance", false)
+	exclude, _ := new_filter(`re:\[WIP\]`, true)
+	set_filters([]Filter{include, include_regex, exclude}, nil)
+	entries := []string{
+		filepath.Join("series", "Frieren"),
+		filepath.Join("series", "Frieren [WIP]"),
+		filepath.Join("series", "SEVERANCE (2022)"),
+		filepath.Join("series", "Dark"),
+	}
+	if kept := filter_entries(entries); strings.Join(kept, ", ") != strings.Join([]string{entries[0], entries[2]}, ", ") {
+		t.Errorf("expected only Frieren and SEVERANCE (2022) to be kept; got %v", kept)
+	}
+
+	exclude_sample, _ := new_filter("*sample*", true)
+	set_filters(nil, []Filter{exclude_sample})
+	for file, expected := range map[string]bool{
+		"ep 01.mkv":        true,
+		"ep 01 sample.mkv": false,
+		"ep 01.srt":        false,
+	} {
+		if is_wanted_media_file(file) != expected {
+			t.Errorf("expected is_wanted_media_file('%s') to be %t", file, expected)
+		}
+	}
+}
+
+func Test_state(t *testing.T) {
+	dir := t.TempDir()
+	entry := filepath.Join(dir, "Frieren")
+	if err := os.MkdirAll(entry, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(entry, "a.mkv")
+	renamed := filepath.Join(entry, "S01E01 Frieren.mkv")
+	if err := os.WriteFile(old, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := new_Args().options.with_defaults()
+	s, err := load_state(filepath.Join(dir, "state.json"), "settings", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log("------------expects entry to be scanned------------")
+	ops := []RenameOp{{old: old, new: renamed, season: 1}}
+	if err := s.record(entry, ops, []AdditionalOptions{options}); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := s.changed_entries([]string{entry}); len(changed) != 1 {
+		t.Errorf("expected entry that was not renamed to not be remembered")
+	}
+	if err := apply_renames(append(ops, RenameOp{old: renamed, new: renamed, season: 1})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.record(entry, ops, []AdditionalOptions{options}); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := load_state(s.path, "other settings", options); len(loaded.Entries) != 0 {
+		t.Errorf("expected state file made with other settings to be ignored")
+	}
+	other_options := options
+	other_options.keep_ep_nums = some[bool](true)
+	if loaded, _ := load_state(s.path, "settings", other_options); len(loaded.Entries) != 1 {
+		t.Errorf("expected state file made with other options to be kept")
+	} else if changed, _ := loaded.changed_entries([]string{entry}); len(changed) != 1 {
+		t.Errorf("expected entry renamed with other options to be scanned again")
+	}
+
+	t.Log("------------expects entry to be skipped------------")
+	loaded, err := load_state(s.path, "settings", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := loaded.changed_entries([]string{entry}); len(changed) != 0 {
+		t.Errorf("expected renamed entry to be skipped; got %v", changed)
+	}
+	var_options := options
+	var_options.keep_ep_nums = none[bool]()
+	if loaded, _ := load_state(s.path, "settings", var_options); len(loaded.Entries) != 1 {
+		t.Errorf("expected state file made with answered options to be kept for var options")
+	} else if changed, _ := loaded.changed_entries([]string{entry}); len(changed) != 0 {
+		t.Errorf("expected entry renamed with answered options to be skipped for var options; got %v", changed)
+	}
+	if err := os.WriteFile(filepath.Join(entry, "b.mkv"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := loaded.changed_entries([]string{entry}); len(changed) != 1 {
+		t.Errorf("expected entry with a new file to be scanned again")
+	}
+}
+
+func Test_run_jobs(t *testing.T) {
+	defer func() { jobs = 1 }()
+
+	for _, count := range []int{1, 4} {
+		jobs = count
+		results := make([]int, 100)
+		err := run_jobs(len(results), func(i int) error {
+			results[i] = i * i
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, result := range results {
+			if result != i*i {
+				t.Errorf("expected result %d of job %d with %d jobs; got %d", i*i, i, count, result)
+			}
+		}
+
+		t.Log("------------expects errors------------")
+		err = run_jobs(100, func(i int) error {
+			if i%10 == 3 {
+				return fmt.Errorf("job %d failed", i)
+			}
+			return nil
+		})
+		if err == nil || err.Error() != "job 3 failed" {
+			t.Errorf("expected error of the first failed job 'job 3 failed' with %d jobs; got %v", count, err)
+		} else {
+			t.Log(err)
+		}
+	}
+}
+
+func Test_transfer_file(t *testing.T) {
+	previous_output := progress_output
+	progress_output = nil
+	defer func() { progress_output = previous_output }()
+
+	dir := t.TempDir()
+	data := []byte(strings.Repeat("0123456789", 1000))
+	for _, test := range []struct {
+		name string
+		part []byte
+	}{
+		{"no part file", nil},
+		{"resumed part file", data[:4321]},
+		{"part file bigger than the original", append(append([]byte{}, data...), "extra"...)},
+		{"part file that does not match", []byte("wrong")},
+	} {
+		old := filepath.Join(dir, "download", "ep 01.mkv")
+		new := filepath.Join(dir, "library", "S01E01.mkv")
+		os.MkdirAll(filepath.Dir(old), 0755)
+		os.MkdirAll(filepath.Dir(new), 0755)
+		os.Remove(new)
+		if err := os.WriteFile(old, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if test.part != nil {
+			if err := os.WriteFile(new+transfer_part_suffix, test.part, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := transfer_file(old, new)
+		if test.name == "part file that does not match" {
+			t.Log("------------expects errors------------")
+			if err == nil {
+				t.Errorf("expected error for %s", test.name)
+			} else if _, err := os.Stat(new + transfer_part_suffix); err == nil {
+				t.Errorf("expected %s to be removed", test.name)
+			} else {
+				t.Log(err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if got, _ := os.ReadFile(new); string(got) != string(data) {
+			t.Errorf("%s: expected transferred file to be the same as the original", test.name)
+		}
+		if _, err := os.Stat(old); err == nil {
+			t.Errorf("%s: expected original to be removed", test.name)
+		}
+		if _, err := os.Stat(new + transfer_part_suffix); err == nil {
+			t.Errorf("%s: expected part file to be renamed", test.name)
+		}
+	}
+
+	old := filepath.Join(dir, "download", "ep 02.mkv")
+	new := filepath.Join(dir, "library", "S01E02.mkv")
+	os.WriteFile(old, data, 0644)
+	os.WriteFile(new, data, 0644)
+	if !is_finished_transfer(old, new) {
+		t.Errorf("expected identical file in another directory to be a finished transfer")
+	}
+}
+
+func Test_link_library(t *testing.T) {
+	dir := t.TempDir()
+	entry := filepath.Join(dir, "downloads", "Frieren")
+	season := filepath.Join(entry, "Season 1")
+	if err := os.MkdirAll(season, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.mkv", "b.mkv"} {
+		if err := os.WriteFile(filepath.Join(season, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := func() []RenameOp {
+		files, _ := os.ReadDir(season)
+		ops := make([]RenameOp, 0)
+		for i, file := range files {
+			ops = append(ops, RenameOp{
+				old:    filepath.Join(season, file.Name()),
+				new:    filepath.Join(season, fmt.Sprintf("S01E%02d Frieren.mkv", i+1)),
+				season: 1,
+			})
+		}
+		return ops
+	}
+
+	root := filepath.Join(dir, "library")
+	link := func() *LinkLibrary {
+		l, err := load_link_library(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.link_entry(entry, l.link_ops(entry, false, plan())); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.prune(); err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	episode := func(num int) string {
+		return filepath.Join(root, "series", "Frieren", "Season 01", fmt.Sprintf("S01E%02d Frieren.mkv", num))
+	}
+
+	l := link()
+	if !is_linked(filepath.Join(season, "a.mkv"), episode(1)) || !is_linked(filepath.Join(season, "b.mkv"), episode(2)) {
+		t.Errorf("expected episodes to be linked in the library")
+	}
+	if _, err := os.Stat(filepath.Join(season, "a.mkv")); err != nil {
+		t.Errorf("expected original files to be untouched")
+	}
+	if len(l.Links) != 2 {
+		t.Errorf("expected 2 links in the manifest; got %d", len(l.Links))
+	}
+
+	// linking again changes nothing
+	if l = link(); len(l.Links) != 2 || !is_linked(filepath.Join(season, "b.mkv"), episode(2)) {
+		t.Errorf("expected linking again to keep the same links")
+	}
+
+	// b.mkv is episode 1 once a.mkv is gone, so the link of episode 2 is stale
+	if err := os.Remove(filepath.Join(season, "a.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	l = link()
+	if !is_linked(filepath.Join(season, "b.mkv"), episode(1)) {
+		t.Errorf("expected link of episode 1 to be replaced")
+	}
+	if _, err := os.Lstat(episode(2)); err == nil {
+		t.Errorf("expected stale link of episode 2 to be removed")
+	}
+	if len(l.Links) != 1 {
+		t.Errorf("expected 1 link in the manifest; got %d", len(l.Links))
+	}
+}
+
+func Test_library_path(t *testing.T) {
+	root := filepath.Join("library")
+	series := filepath.Join("in", "series", "Frieren (2023)")
+	movie := filepath.Join("in", "movies", "Heat (1995)")
+	tests := []struct {
+		entry    string
+		is_movie bool
+		op       RenameOp
+		expected string
+	}{
+		{series, false,
+			RenameOp{old: filepath.Join(series, "Season 1", "a.mkv"), new: filepath.Join(series, "Season 1", "S01E01 Frieren.mkv"), season: 1},
+			filepath.Join(root, "series", "Frieren (2023)", "Season 01", "S01E01 Frieren.mkv")},
+		{series, false,
+			RenameOp{old: filepath.Join(series, "Specials", "a.mkv"), new: filepath.Join(series, "Specials", "S00E01 Frieren.mkv"), season: 0},
+			filepath.Join(root, "series", "Frieren (2023)", "Season 00", "S00E01 Frieren.mkv")},
+		{filepath.Join("in", "series", "01. Frieren (2023) {tvdb-424536}"), false,
+			RenameOp{old: filepath.Join("in", "series", "01. Frieren (2023) {tvdb-424536}", "Season 1", "a.mkv"), new: "S01E01 Frieren.mkv", season: 1},
+			filepath.Join(root, "series", "Frieren (2023) [tvdbid-424536]", "Season 01", "S01E01 Frieren.mkv")},
+		{series, false,
+			RenameOp{old: filepath.Join(series, "Movie", "m.mkv"), new: filepath.Join(series, "Movie", "Frieren Movie.mkv"), season: -1},
+			filepath.Join(root, "movies", "Frieren Movie", "Frieren Movie.mkv")},
+		{movie, true,
+			RenameOp{old: filepath.Join(movie, "heat.mkv"), new: filepath.Join(movie, "Heat.mkv"), season: -1},
+			filepath.Join(root, "movies", "Heat (1995)", "Heat.mkv")},
+		{movie, true,
+			RenameOp{old: filepath.Join("in", "movies", "Heat", "Heat.1995.1080p.mkv"), new: filepath.Join("in", "movies", "Heat", "Heat.mkv"), season: -1},
+			filepath.Join(root, "movies", "Heat (1995)", "Heat.mkv")},
+	}
+	for _, test := range tests {
+		if got := library_path(root, test.entry, test.is_movie, test.op); got != test.expected {
+			t.Errorf("expected '%s' to go to '%s'; got '%s'", test.op.old, test.expected, got)
+		}
+	}
+}
+
+func Test_naming_scheme_compiler(t *testing.T) {
+	t.Log("------------expects errors------------")
+	for scheme, column := range map[string]int{
+		"S<season_num>E<episode>": 16,
+		"<p-0>":                   2,
+		"<self: 10,9>":            8,
+		"<resolution: 2>":         14,
+		"<season_num: '(\\d+)'>":  14,
+		"<self: '(a)' x>":         14,
+		"<p>é<parent-parent: 1,>": 21,
+		"[<self>":                 1,
+		"<self>a]":                8,
+		"<self ? p>":              7,
+		"<self: 1 ?? nope>":       13,
+		"<self ?? >":              10,
+		"<self | nope>":           9,
+		"<self | replace:'a'>":    9,
+		"<self | pad:'3'>":        13,
+		"<self | replace:'a',2>":  21,
+		"<self | upper:1>":        9,
+		"<self | >":               9,
+	} {
+		_, err := parse_naming_scheme(scheme)
+		scheme_err, ok := err.(*SchemeError)
+		if !ok {
+			t.Errorf("expected a naming scheme error for '%s'; got %v", scheme, err)
+			continue
+		}
+		if scheme_err.column() != column {
+			t.Errorf("expected error for '%s' at column %d; got %d", scheme, column, scheme_err.column())
+		}
+		t.Log(err)
+	}
+
+	t.Log("------------expects success------------")
+	path := filepath.Join("Show (2019)", "Season 1", "Show.S01E04E05.<p>.1080p.mkv")
+	tests := map[string]string{
+		`<self: '\.S\d+E(\d+)'>`:                                "04",
+		`<self: 5>`:                                             "S",
+		`<self: 0,3> <self: 19,99> <self: 99>`:                  "Show 1080p Show.S01E04E05.<p>.1080p",
+		`<self: 15,17>`:                                         "<p>",
+		`<parent: 'x(\d+)|(\d+)'>`:                              "1",
+		`<p-2: '\((\d{4})\)'> <p-2: '<(\d+)>'>`:                 "2019 Show (2019)",
+		`E<episode_num: 3>-E<episode_end>`:                      "E004-E05",
+		`<self: 'S01(E04)'>`:                                    "E04",
+		`<self: 0,3>[ - <episode_title>]`:                       "Show - <p>",
+		`<self: 0,3>[ - <group>]`:                               "Show",
+		`<source ?? resolution>`:                                "1080p",
+		`<parent: 'x(\d+)' ?? p-2: 0,3>`:                        "Show",
+		`[<source>[ <codec>] ]<resolution>`:                     "1080p",
+		`[<resolution>[ <codec>]]`:                              "1080p",
+		`\[<resolution>\] <self: 99>`:                           "[1080p] Show.S01E04E05.<p>.1080p",
+		`A<codec ?? source>B`:                                   "AB",
+		`<parent | upper>`:                                      "SEASON 1",
+		`<parent | lower | title>`:                              "Season 1",
+		`<self: 0,3 | lower | replace:'s','z'>`:                 "zhow",
+		`<p-2 | slug>`:                                          "show-2019",
+		`<season_num | pad:3>`:                                  "001",
+		`<parent: '(\d+)' | pad:2>`:                             "01",
+		`<parent | replace:'Season ','S' | pad:2>`:              "S1",
+		`[<group | upper>]x`:                                    "x",
+		`<p-2 | replace:'Show (2019)',' ' | trim ?? self: 0,3>`: "Show",
+	}
+	for scheme, expected := range tests {
+		name, err := generate_new_name(some[string](scheme), 2, 1, 2, 4, "title", path)
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %s", scheme, err)
+			continue
+		}
+		if got := strings.TrimSuffix(filepath.Base(name), ".mkv"); got != expected {
+			t.Errorf("expected '%s' to render '%s'; got '%s'", scheme, expected, got)
+		}
+	}
+	for _, scheme := range []string{`<self | replace:'.','/'>`, `<parent | replace:' ','\'>`} {
+		if _, err := generate_new_name(some[string](scheme), 2, 1, 2, 4, "title", path); err == nil {
+			t.Errorf("expected a path separator error for '%s'", scheme)
+		}
+	}
+}
+
+func Test_template_naming_scheme(t *testing.T) {
+	path := filepath.Join("Show (2019)", "Season 1", "Show.S01E04E05.1080p.mkv")
+	t.Log("------------expects errors------------")
+	for _, scheme := range []string{
+		"tmpl:S{{.Season",
+		"tmpl:{{.Nope}}",
+		"tmpl:{{.Info.nope}}",
+		"tmpl:{{exec}}",
+		"tmpl:{{index .Parents 0}}/x",
+	} {
+		if _, err := generate_new_name(some[string](scheme), 2, 1, 2, 4, "Title", path); err == nil {
+			t.Errorf("expected an error for '%s'", scheme)
+		} else {
+			t.Log(err)
+		}
+	}
+
+	t.Log("------------expects success------------")
+	for scheme, expected := range map[string]string{
+		"tmpl:S{{pad 2 .Season}}E{{pad .EpisodePad .Episode}}-E{{pad 3 .EpisodeEnd}}": "S01E04-E005",
+		"tmpl:{{index .Parents 1 | slug}} {{.Info.resolution}}":                      "show-2019 1080p",
+		"tmpl:{{default .Title .Info.group}}":                                        "Title",
+		`tmpl:{{match "S\\d+(E\\d+)" .Self | lower}}`:                                "e04",
+		`tmpl:{{.Title}}{{with .Info.episode_title}} - {{.}}{{end}}`:                 "Title",
+		`tmpl:<self> {{replace "." " " .Self | trim}}`:                               "<self> Show S01E04E05 1080p",
+	} {
+		name, err := generate_new_name(some[string](scheme), 2, 1, 2, 4, "Title", path)
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %s", scheme, err)
+			continue
+		}
+		if got := strings.TrimSuffix(filepath.Base(name), ".mkv"); got != expected {
+			t.Errorf("expected '%s' to render '%s'; got '%s'", scheme, expected, got)
+		}
+	}
+}
+
+func Test_naming_presets(t *testing.T) {
+	t.Log("------------expects errors------------")
+	for _, scheme := range []string{"preset:nope", "preset:"} {
+		if err := validate_naming_scheme(scheme); err == nil {
+			t.Errorf("expected an error for '%s'", scheme)
+		} else {
+			t.Log(err)
+		}
+	}
+	if _, err := parse_args([]string{"-r", t.TempDir(), "-ns", "preset:nope"}); err == nil {
+		t.Errorf("expected an error for -ns preset:nope")
+	}
+
+	t.Log("------------expects success------------")
+	dir := t.TempDir()
+	for _, path := range []string{
+		"Show (2019)/Season 1/Show.S01E01.Pilot.1080p.mkv",
+		"Show (2019)/Season 1/Show.S01E02E03.mkv",
+		"Show (2019)/Season 2/Show.S02E01.mkv",
+		"Show (2019)/Specials/Show.S00E01.mkv",
+		"Show (2019)/Movie (2021)/movie.mkv",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan_names := func(preset string) []string {
+		options := new_Args().options.with_defaults()
+		options.has_season_0 = some[bool](true)
+		options.naming_scheme = some[string](preset)
+		info, err := series_rename_prereqs(filepath.Join(dir, "Show (2019)"), kind_multiple_season_with_movies, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := info.plan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(ops))
+		for _, op := range ops {
+			names = append(names, filepath.Base(op.new))
+		}
+		return names
+	}
+	for preset, expected := range map[string][]string{
+		"preset:plex": {
+			"Show - s00e01.mkv",
+			"Show - s01e01 - Pilot.mkv",
+			"Show - s01e02-e03.mkv",
+			"Show - s02e01.mkv",
+			"Movie (2021).mkv",
+		},
+		"preset:kodi": {
+			"Show S00E01.mkv",
+			"Show S01E01 Pilot.mkv",
+			"Show S01E02-E03.mkv",
+			"Show S02E01.mkv",
+			"Movie (2021).mkv",
+		},
+		"preset:anime-absolute": {
+			"Show - S00E01.mkv",
+			"Show - 001 - Pilot.mkv",
+			"Show - 002-003.mkv",
+			"Show - 004.mkv",
+			"Movie (2021).mkv",
+		},
+	} {
+		if got := plan_names(preset); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("expected %s to plan\n%s\ngot\n%s", preset, strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	movie := filepath.Join(dir, "Some Movie (2004)")
+	if got := preset_movie_name(clean_title(filepath.Base(movie)), movie, "some.movie.mkv", "plex"); got != "Some Movie (2004)" {
+		t.Errorf("expected 'Some Movie (2004)'; got '%s'", got)
+	}
+	if got := preset_movie_name("Some Movie", "Some Movie", "Some.Movie.1999.1080p.mkv", "plex"); got != "Some Movie (1999)" {
+		t.Errorf("expected 'Some Movie (1999)'; got '%s'", got)
+	}
+}
+
+func Test_import_naming_scheme(t *testing.T) {
+	t.Log("------------expects errors------------")
+	for _, format := range []string{"{Series Title", "S{season:00}}", "{n.upperInitial()}", "Show - {Custom Formats}"} {
+		if _, err := import_naming_scheme(format); err == nil {
+			t.Errorf("expected an error for '%s'", format)
+		} else {
+			t.Log(err)
+		}
+	}
+
+	t.Log("------------expects success------------")
+	for format, expected := range map[string]string{
+		"{Series Title} - S{season:00}E{episode:00} - {Episode Title}": "<title> - S<season_num: 2>E<episode_num: 2> - <episode_title>",
+		"{Movie Title} ({Release Year})":                               "<title> (<year>)",
+		"{series.title}.S{season}E{episode:000}":                       "<title | lower | replace:' ','.'>.S<season_num: 0>E<episode_num: 3>",
+		"{Series Title} {[Quality Title]}{-Release Group}":             `<title> [\[<source>[-<resolution>]\]][-<group>]`,
+		"{n}/Season {s}/{n} - {s00e00} - {t} [{vf}]":                   `<title> - S<season_num: 2>E<episode_num: 2> - <episode_title> \[<video_res>\]`,
+		"{n} - {absolute.pad(3)} {Preferred Words}":                    "<title> - <absolute_num: 3>",
+		"{n} {n.upperInitial()}":                                       "<title>",
+		"{n.upperInitial()} - {s00e00}":                                "S<season_num: 2>E<episode_num: 2>",
+		"{n} - {Custom Formats} - {t}":                                 "<title> - <episode_title>",
+		"{n} [{Custom Formats}] {t}":                                   "<title> <episode_title>",
+	} {
+		imported, err := import_naming_scheme(format)
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %s", format, err)
+		} else if imported.scheme != expected {
+			t.Errorf("expected '%s' to be translated to '%s'; got '%s'", format, expected, imported.scheme)
+		}
+	}
+
+	imported, _ := import_naming_scheme("{n}/{Series Title} {Custom Formats} {Quality Full}")
+	if imported.dirs != "{n}/" || strings.Join(imported.unsupported, ",") != "{Custom Formats}" || strings.Join(imported.approximated, ",") != "{Quality Full}" {
+		t.Errorf("expected dirs '{n}/', unsupported {Custom Formats}, approximated {Quality Full}; got '%s', %v, %v", imported.dirs, imported.unsupported, imported.approximated)
+	}
+	for _, format := range []string{"{Series TitleYear}", "{Movie TitleYear}"} {
+		if imported, _ := import_naming_scheme(format); strings.Join(imported.approximated, ",") != format {
+			t.Errorf("expected %s to be approximated; got %v", format, imported.approximated)
+		}
+	}
+}
+
+func Test_movie_naming_scheme(t *testing.T) {
+	defer func() { movie_naming_scheme = some[string]("default") }()
+
+	t.Log("------------expects errors------------")
+	for _, scheme := range []string{"<title> <season_num>", "<title> [<episode_num>]", "<nope>"} {
+		if err := validate_movie_naming_scheme(scheme); err == nil {
+			t.Errorf("expected an error for '%s'", scheme)
+		} else {
+			t.Log(err)
+		}
+	}
+
+	t.Log("------------expects success------------")
+	dir := t.TempDir()
+	for _, path := range []string{
+		"Collection/Movie A (2001)/a.mkv",
+		"Collection/Movie B/Movie.B.1999.Directors.Cut.1080p.mkv",
+		"Collection/Movie D Part 2 (2005)/d.mkv",
+		"Movie C (2004) Extended/movie.c.cd1.mkv",
+		"Show (2019)/Season 1/Show.S01E01.mkv",
+		"Show (2019)/The Movie (2021)/movie.mkv",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan_names := func() []string {
+		names := make([]string, 0)
+		for _, entry := range []struct {
+			path string
+			kind MovieKind
+		}{{"Collection", kind_movie_set}, {"Movie C (2004) Extended", kind_standalone}} {
+			info, err := movie_rename_prereqs(filepath.Join(dir, entry.path), entry.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ops, err := info.plan()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, op := range ops {
+				names = append(names, filepath.Base(op.new))
+			}
+		}
+		options := new_Args().options.with_defaults()
+		info, err := series_rename_prereqs(filepath.Join(dir, "Show (2019)"), kind_multiple_season_with_movies, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := info.plan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, filepath.Base(ops[len(ops)-1].new))
+		return names
+	}
+	for scheme, expected := range map[string][]string{
+		"default": {
+			"Movie A (2001).mkv",
+			"Movie B (1999) {edition-Director's Cut}.mkv",
+			"Movie D Part 2 (2005).mkv",
+			"Movie C (2004) {edition-Extended}.mkv",
+			"Show The Movie (2021).mkv",
+		},
+		"[<collection | upper> - ]<title>[ (<year>)][ {<edition>}][ pt<part>]": {
+			"COLLECTION - Movie A (2001).mkv",
+			"COLLECTION - Movie B (1999) {Director's Cut}.mkv",
+			"COLLECTION - Movie D Part 2 (2005) pt2.mkv",
+			"Movie C (2004) {Extended} pt1.mkv",
+			"SHOW - The Movie (2021).mkv",
+		},
+	} {
+		movie_naming_scheme = some[string](scheme)
+		if got := plan_names(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("expected '%s' to plan\n%s\ngot\n%s", scheme, strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+	for dir, expected := range map[string]string{
+		"Movie (2016) Director's Cut": "Movie",
+		"Movie - Extended (2004)":     "Movie",
+		"Uncut Gems (2019)":           "Uncut Gems",
+		"The Extended Family (2020)":  "The Extended Family",
+		"Movie (Unrated) (2004)":      "Movie",
+	} {
+		if got := movie_title(dir); got != expected {
+			t.Errorf("expected the title of '%s' to be '%s'; got '%s'", dir, expected, got)
+		}
+	}
+}
+
+func Test_media_tags(t *testing.T) {
+	defer func() { media_tag_style = none[string]() }()
+
+	for name, expected := range map[string]MediaTags{
+		"Movie (2016) {edition-Director's Cut} [imdbid-tt0123456]": {year: 2016, edition: "Director's Cut", ids: map[string]string{"imdb": "tt0123456"}},
+		"Movie (2016) {imdb-tt2016001} {tmdb-12345}":               {year: 2016, ids: map[string]string{"imdb": "tt2016001", "tmdb": "12345"}},
+		"Show [tvdbid=81189]":                                      {year: -1, ids: map[string]string{"tvdb": "81189"}},
+		"Movie.2010.Extended.1080p":                                {year: 2010, edition: "Extended", ids: map[string]string{}},
+		"The Extended Family (2020)":                               {year: 2020, ids: map[string]string{}},
+		"Uncut Gems (2019) [tmdbid-473033]":                        {year: 2019, ids: map[string]string{"tmdb": "473033"}},
+	} {
+		got := parse_media_tags(name)
+		if got.year != expected.year || got.edition != expected.edition || fmt.Sprint(got.ids) != fmt.Sprint(expected.ids) {
+			t.Errorf("expected '%s' to have tags %+v; got %+v", name, expected, got)
+		}
+	}
+
+	if got := clean_title("Movie (2016) {edition-Director's Cut} [imdbid-tt0123456]"); got != "Movie" {
+		t.Errorf("expected clean_title to strip the tags; got '%s'", got)
+	}
+
+	// edition words in titles are not editions, without any flags
+	dir := t.TempDir()
+	for movie, expected := range map[string]string{
+		"The Extended Family (2020)":        "The Extended Family (2020).mkv",
+		"Uncut Gems (2019) [tmdbid-473033]": "Uncut Gems (2019) [tmdbid-473033].mkv",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, movie), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, movie, "movie.mkv"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := movie_rename_prereqs(filepath.Join(dir, movie), kind_standalone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := info.plan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filepath.Base(ops[0].new); got != expected {
+			t.Errorf("expected '%s' to be renamed to '%s'; got '%s'", movie, expected, got)
+		}
+	}
+
+	tags := parse_media_tags("Movie (2016) {edition-Director's Cut} [imdbid-tt0123456] [tmdbid-12345]")
+	for style, expected := range map[string]string{
+		"plex":     "Movie (2016) {edition-Director's Cut} {imdb-tt0123456} {tmdb-12345}",
+		"jellyfin": "Movie (2016) {edition-Director's Cut} [imdbid-tt0123456] [tmdbid-12345]",
+		"emby":     "Movie (2016) {edition-Director's Cut} [imdbid=tt0123456] [tmdbid=12345]",
+		"none":     "Movie (2016)",
+	} {
+		if got := tags.tagged_name("Movie", style); got != expected {
+			t.Errorf("expected %s style name '%s'; got '%s'", style, expected, got)
+		}
+	}
+
+	media_tag_style = some[string]("plex")
+	if style := tag_style_for(some[string]("preset:jellyfin")); style != "plex" {
+		t.Errorf("expected --tag-style to win over the preset's style; got '%s'", style)
+	}
+	scheme, err := compile_naming_scheme("<title>[ <ids>] S<season_num>E<episode_num>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := scheme.render(SchemeContext{
+		season_pad: 2, season_num: 1, ep_pad: 2, ep_num: 3,
+		abs_path: filepath.Join("Show (2019) [tvdbid-81189]", "Season 1", "Show.S01E03.mkv"),
+		title:    "Show",
+		entry:    "Show (2019) [tvdbid-81189]",
+	})
+	if err != nil || name != "Show {tvdb-81189} S01E03" {
+		t.Errorf("expected 'Show {tvdb-81189} S01E03'; got '%s' (%v)", name, err)
+	}
+}
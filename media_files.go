@@ -1,9 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
-	"regexp"
 	"path/filepath"
+	"strings"
 )
 
 type MediaFiles interface {
@@ -11,88 +12,167 @@ type MediaFiles interface {
 }
 
 type Movies struct {
-	standalone []string
-	movie_set  []string
+	entries         map[MovieKind][]string
+	overrides       []TypeOverride
+	classifications []Classification
 }
 type Series struct {
-	named_seasons               []string
-	single_season_no_movies     []string
-	single_season_with_movies   []string
-	multiple_season_no_movies   []string
-	multiple_season_with_movies []string
+	entries         map[SeriesKind][]string
+	overrides       []TypeOverride
+	classifications []Classification
 }
 
+// why an entry was categorized as its type. kind is empty if the entry matched no type
+type Classification struct {
+	path   string
+	kind   string
+	rule   string
+	reason string
+}
+
+// forces entries whose name or path matches pattern (a glob) to be categorized as kind
+type TypeOverride struct {
+	pattern string
+	kind    string
+}
+
+// name of the file in an entry directory that forces the entry's type. it should only contain the type
+const type_marker_file = ".gorn-type"
+
 func (movie *Movies) split_by_type(movie_entries []string) error {
-	for _, movie_entry := range movie_entries {
-		files, err := os.ReadDir(movie_entry)
+	// entries are classified with up to --jobs at a time, then added in order
+	classifications := make([]Classification, len(movie_entries))
+	err := run_jobs(len(movie_entries), func(i int) error {
+		movie_entry := movie_entries[i]
+		kind, reason, err := find_type_override(movie_entry, movie.overrides, movie_rules)
 		if err != nil {
 			return err
 		}
+		if kind != "" {
+			classifications[i] = Classification{movie_entry, kind, "override", reason}
+			return nil
+		}
 
-		extras_pattern := regexp.MustCompile(`^(?i)specials?|extras?|trailers?`)
-
-		for _, file := range files {
-			if file.IsDir() && !extras_pattern.MatchString(file.Name()) {
-				movie.movie_set = append(movie.movie_set, movie_entry)
-				break
-
-			} else if is_media_file(file.Name()) {
-				movie.standalone = append(movie.standalone, movie_entry)
-				break
-			} 
+		rule, reason, err := classify(movie_entry, movie_rules, movie_extras_pattern)
+		if err != nil {
+			return err
+		}
+		if rule == nil {
+			classifications[i] = Classification{movie_entry, "", "none", "no rule matched: no media files or movie subdirectories found"}
+			return nil
 		}
+		classifications[i] = Classification{movie_entry, rule.Name, fmt.Sprintf("%s rule '%s'", rule.source, rule.Name), reason}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range classifications {
+		movie.add(c)
 	}
 	return nil
 }
 
+func (movie *Movies) add(c Classification) {
+	if c.kind != "" {
+		if movie.entries == nil {
+			movie.entries = make(map[MovieKind][]string)
+		}
+		movie.entries[MovieKind(c.kind)] = append(movie.entries[MovieKind(c.kind)], c.path)
+	}
+	movie.classifications = append(movie.classifications, c)
+}
+
 func (series *Series) split_by_type(series_entries []string) error {
-	for _, series_entry := range series_entries {
-		files, err := os.ReadDir(series_entry)
+	// entries are classified with up to --jobs at a time, then added in order
+	classifications := make([]Classification, len(series_entries))
+	err := run_jobs(len(series_entries), func(i int) error {
+		series_entry := series_entries[i]
+		kind, reason, err := find_type_override(series_entry, series.overrides, series_rules)
 		if err != nil {
 			return err
 		}
-
-		named_seasons_pattern := regexp.MustCompile(`^\d+\.\s+(.*)$`)
-		seasonal_pattern := regexp.MustCompile(`^(?i)season\s+(\d+)`)
-		possibly_single_season := false
-		for _, file := range files {
-			if file.IsDir() {
-				if file.Name() == filepath.Base(series_entry) {
-					series.single_season_with_movies = append(series.single_season_with_movies, series_entry)
-					possibly_single_season = false
-					break
-
-				} else if named_seasons_pattern.MatchString(file.Name()) {
-					series.named_seasons = append(series.named_seasons, series_entry)
-					possibly_single_season = false
-					break
-
-				} else if seasonal_pattern.MatchString(file.Name()) {
-					has_movie, err := has_movie(series_entry)
-					if err != nil {
-						return err
-					}
-
-					if has_movie {
-						series.multiple_season_with_movies = append(series.multiple_season_with_movies, series_entry)
-						possibly_single_season = false
-						break
-
-					} else {
-						series.multiple_season_no_movies = append(series.multiple_season_no_movies, series_entry)
-						possibly_single_season = false
-						break
-					}
-				}
-
-			} else if is_media_file(file.Name()) && !possibly_single_season {
-				possibly_single_season = true
-			}
+		if kind != "" {
+			classifications[i] = Classification{series_entry, kind, "override", reason}
+			return nil
 		}
 
-		if possibly_single_season {
-			series.single_season_no_movies = append(series.single_season_no_movies, series_entry)
+		rule, reason, err := classify(series_entry, series_rules, series_extras_pattern)
+		if err != nil {
+			return err
 		}
+		if rule == nil {
+			classifications[i] = Classification{series_entry, "", "none", "no rule matched: no season subdirectories or media files found"}
+			return nil
+		}
+		classifications[i] = Classification{series_entry, rule.Name, fmt.Sprintf("%s rule '%s'", rule.source, rule.Name), reason}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range classifications {
+		series.add(c)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (series *Series) add(c Classification) {
+	if c.kind != "" {
+		if series.entries == nil {
+			series.entries = make(map[SeriesKind][]string)
+		}
+		series.entries[SeriesKind(c.kind)] = append(series.entries[SeriesKind(c.kind)], c.path)
+	}
+	series.classifications = append(series.classifications, c)
+}
+
+// find_type_override returns the type forced on an entry by a --type flag or a type marker file.
+// --type flags take precedence over marker files. kind is empty if the entry has no override
+func find_type_override(entry string, overrides []TypeOverride, rules []*TypeRule) (string, string, error) {
+	for _, override := range overrides {
+		if !matches_glob(override.pattern, entry) {
+			continue
+		}
+		if find_rule(rules, override.kind) == nil {
+			return "", "", fmt.Errorf("--type '%s=%s' matches %s but %s is not a valid type for it", override.pattern, override.kind, entry, override.kind)
+		}
+		return override.kind, fmt.Sprintf("--type pattern '%s' matches the entry", override.pattern), nil
+	}
+
+	marker, err := os.ReadFile(filepath.Join(entry, type_marker_file))
+	if os.IsNotExist(err) {
+		return "", "", nil
+	} else if err != nil {
+		return "", "", err
+	}
+	kind := strings.TrimSpace(string(marker))
+	if find_rule(rules, kind) == nil {
+		return "", "", fmt.Errorf("invalid type '%s' in %s", kind, filepath.Join(entry, type_marker_file))
+	}
+	return kind, fmt.Sprintf("marker file '%s' in the entry says '%s'", type_marker_file, kind), nil
+}
+
+// matches_glob matches a glob against the base name of path, or the whole path if the glob has a path separator
+func matches_glob(pattern string, path string) bool {
+	if strings.ContainsAny(pattern, `/\`) {
+		matched, _ := filepath.Match(filepath.FromSlash(pattern), path)
+		return matched
+	}
+	matched, _ := filepath.Match(pattern, filepath.Base(path))
+	return matched
+}
+
+// print_classifications explains which rule categorized each entry and why
+func print_classifications(classifications []Classification) {
+	for _, c := range classifications {
+		kind := c.kind
+		if kind == "" {
+			kind = "(not categorized)"
+		}
+		fmt.Println("\t", c.path)
+		fmt.Println("\t\t type:", kind)
+		fmt.Println("\t\t rule:", c.rule)
+		fmt.Println("\t\t why: ", c.reason)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MediaTags are the year, edition, and provider ids media servers match a movie or series by.
+// they are read from directory names in both the Plex and Jellyfin forms
+//
+//	Movie (2016) {edition-Director's Cut} {imdb-tt0123456}
+//	Movie (2016) [imdbid-tt0123456] [tmdbid-12345]
+//
+// year is -1 if it was not found
+type MediaTags struct {
+	year    int
+	edition string
+	// provider ids by provider (imdb, tmdb, tvdb)
+	ids map[string]string
+}
+
+// providers in the order their ids are written
+var media_id_providers = []string{"imdb", "tmdb", "tvdb"}
+
+// how year, edition, and provider ids are written in names (--tag-style)
+//
+//	plex:     Movie (2016) {edition-Director's Cut} {imdb-tt0123456}
+//	jellyfin: Movie (2016) {edition-Director's Cut} [imdbid-tt0123456]
+//	emby:     Movie (2016) {edition-Director's Cut} [imdbid=tt0123456]
+//	none:     Movie (2016)
+var media_tag_styles = []string{"plex", "jellyfin", "emby", "none"}
+
+// the tag style given by --tag-style. without it, movies named by a preset use the preset's style and
+// everything else uses default_media_tag_style
+var media_tag_style = none[string]()
+
+const default_media_tag_style = "jellyfin"
+
+var (
+	media_edition_tag_pattern = regexp.MustCompile(`(?i)\s*\{edition-([^}]+)\}`)
+	media_id_tag_pattern      = regexp.MustCompile(`(?i)\s*\{(imdb|tmdb|tvdb)-([^}\s]+)\}|\s*\[(imdb|tmdb|tvdb)id[-=]([^\]\s]+)\]`)
+)
+
+// parse_media_tags reads the year, edition, and provider ids from the name of a movie or series directory
+func parse_media_tags(name string) MediaTags {
+	tags := MediaTags{ids: make(map[string]string)}
+	for _, match := range media_id_tag_pattern.FindAllStringSubmatch(name, -1) {
+		provider, id := match[1], match[2]
+		if provider == "" {
+			provider, id = match[3], match[4]
+		}
+		tags.ids[strings.ToLower(provider)] = id
+	}
+	// the tags are taken out so ids like tt2016001 can't be mistaken for the year
+	release := parse_release_name(strip_media_tags(name))
+	tags.year, tags.edition = release.year, release.edition
+	if match := media_edition_tag_pattern.FindStringSubmatch(name); match != nil {
+		tags.edition = strings.TrimSpace(match[1])
+	}
+	return tags
+}
+
+// movie_media_tags are the tags of a movie's directory, with what is missing read from the movie's file name
+func movie_media_tags(dir string, file string) MediaTags {
+	tags := parse_media_tags(filepath.Base(dir))
+	return tags.or(parse_media_tags(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))))
+}
+
+// or fills what is missing from tags with other
+func (tags MediaTags) or(other MediaTags) MediaTags {
+	if tags.year == -1 {
+		tags.year = other.year
+	}
+	if tags.edition == "" {
+		tags.edition = other.edition
+	}
+	for provider, id := range other.ids {
+		if _, ok := tags.ids[provider]; !ok {
+			tags.ids[provider] = id
+		}
+	}
+	return tags
+}
+
+// strip_media_tags removes provider id tags, and edition tags in the Plex form, from a name
+func strip_media_tags(name string) string {
+	name = media_id_tag_pattern.ReplaceAllString(name, "")
+	return strings.TrimSpace(media_edition_tag_pattern.ReplaceAllString(name, ""))
+}
+
+// edition_tag is the edition in the Plex form "{edition-Director's Cut}", which is the same for every style
+func (tags MediaTags) edition_tag(style string) string {
+	if tags.edition == "" || style == "none" {
+		return ""
+	}
+	return fmt.Sprintf("{edition-%s}", tags.edition)
+}
+
+// id_tags is every provider id written in a tag style, separated by spaces
+func (tags MediaTags) id_tags(style string) string {
+	format := map[string]string{
+		"plex":     "{%s-%s}",
+		"jellyfin": "[%sid-%s]",
+		"emby":     "[%sid=%s]",
+	}[style]
+	if format == "" {
+		return ""
+	}
+	written := make([]string, 0, len(tags.ids))
+	for _, provider := range media_id_providers {
+		if id, ok := tags.ids[provider]; ok {
+			written = append(written, fmt.Sprintf(format, provider, id))
+		}
+	}
+	return strings.Join(written, " ")
+}
+
+// tagged_name is a title followed by its year, edition, and provider ids in a tag style.
+// the year is left out if the title already has it
+func (tags MediaTags) tagged_name(title string, style string) string {
+	name := strings.TrimSpace(title)
+	if tags.year != -1 && !strings.Contains(name, fmt.Sprintf("(%d)", tags.year)) {
+		name = fmt.Sprintf("%s (%d)", name, tags.year)
+	}
+	for _, tag := range []string{tags.edition_tag(style), tags.id_tags(style)} {
+		if tag != "" {
+			name += " " + tag
+		}
+	}
+	return name
+}
+
+// tokens returns the tokens of the tags for use in naming schemes. <ids> and <edition_tag> are written
+// in the --tag-style
+func (tags MediaTags) tokens() map[string]string {
+	style := tag_style_for(none[string]())
+	return map[string]string{
+		"imdb_id":     tags.ids["imdb"],
+		"tmdb_id":     tags.ids["tmdb"],
+		"tvdb_id":     tags.ids["tvdb"],
+		"ids":         tags.id_tags(style),
+		"edition_tag": tags.edition_tag(style),
+	}
+}
+
+// tag_style_for is the tag style of names given by a naming scheme
+func tag_style_for(naming_scheme Option[string]) string {
+	if style, err := media_tag_style.get(); err == nil {
+		return style
+	}
+	ns, _ := naming_scheme.get()
+	if preset, is_preset, err := find_preset(ns); is_preset && err == nil {
+		return preset.tag_style
+	}
+	return default_media_tag_style
+}
+
+func validate_media_tag_style(style string) error {
+	for _, valid := range media_tag_styles {
+		if style == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid tag style '%s'. Must be one of %s", style, strings.Join(media_tag_styles, ", "))
+}
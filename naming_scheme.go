@@ -0,0 +1,741 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamingScheme is a naming scheme parsed once into literal text and tokens.
+// validating and renaming both go through it so they can't disagree on what a scheme means
+//
+//	scheme   = { text | token | group }
+//	group    = "[" scheme "]"
+//	token    = "<" choice { "??" choice } ">"
+//	choice   = name [ ":" value ] { "|" filter }
+//	name     = "season_num" | "episode_num" | "episode_end" | "absolute_num" | "absolute_end" | "self" | "parent" { "-parent" } | "p" [ "-" number ] | info token
+//	value    = number | number "," number | "'" regex "'"
+//	filter   = filter name [ ":" arg { "," arg } ]
+//	arg      = number | "'" text "'"
+//
+// spaces are allowed around the name, the ':', the ',', the '??', the '|', and the value.
+// a '\' before '[', ']', or '<' makes it text
+//
+// a scheme starting with "tmpl:" is a go text/template instead (see naming_template.go)
+type NamingScheme struct {
+	source   string
+	nodes    []SchemeNode
+	template *template.Template
+}
+
+// SchemeNode is text, a token, or an optional group. a group has its nodes in group
+// and is left out of the name when any token directly in it resolves empty.
+// a token that resolves empty is replaced by the first of its fallbacks that doesn't, or the last one if they all do
+type SchemeNode struct {
+	pos   int
+	text  string
+	token string
+	// directories up from the media file for parent tokens (0 for self)
+	parent    int
+	value     SchemeValue
+	filters   []SchemeFilter
+	fallbacks []SchemeNode
+	optional  bool
+	group     []SchemeNode
+}
+
+const (
+	value_none = iota
+	value_pad
+	value_range
+	value_regex
+)
+
+// SchemeValue is what comes after the ':' of a token. start and end of a range are inclusive.
+// a regex is split into parts by its outermost '|' and the first part that matches gives its capture group
+type SchemeValue struct {
+	kind    int
+	pos     int
+	pad     int
+	start   int
+	end     int
+	regexes []*regexp.Regexp
+}
+
+// SchemeError is an error at a position in a naming scheme. Error shows the scheme with a caret under the position
+type SchemeError struct {
+	scheme string
+	pos    int
+	msg    string
+}
+
+func (e *SchemeError) column() int {
+	return utf8.RuneCountInString(e.scheme[:e.pos]) + 1
+}
+
+// short is the error on one line, for places that can't show the caret like the tui status line
+func (e *SchemeError) short() string {
+	return fmt.Sprintf("%s at column %d", e.msg, e.column())
+}
+
+func (e *SchemeError) Error() string {
+	return fmt.Sprintf("%s\n\t%s\n\t%s^", e.short(), e.scheme, strings.Repeat(" ", e.column()-1))
+}
+
+var (
+	scheme_number_tokens = map[string]bool{"season_num": true, "episode_num": true, "episode_end": true, "absolute_num": true, "absolute_end": true}
+	scheme_parent_long   = regexp.MustCompile(`^parent(-parent)*$`)
+	scheme_parent_short  = regexp.MustCompile(`^p(?:-(\d+))?$`)
+	scheme_range         = regexp.MustCompile(`^(\d+)(?:\s*,\s*(\d+))?$`)
+)
+
+// tokens filled from where the media file is in its entry
+var context_token_names = []string{"special_kind", "title", "collection", "imdb_id", "tmdb_id", "tvdb_id", "ids", "edition_tag"}
+
+// is_info_token reports whether a token is filled from the release name, the media file, or its entry.
+// these take no value
+func is_info_token(name string) bool {
+	for _, names := range [][]string{release_token_names, probe_token_names, context_token_names} {
+		for _, info := range names {
+			if name == info {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compiled naming schemes by source since the same scheme is rendered for every file
+var (
+	compiled_schemes      = make(map[string]*NamingScheme)
+	compiled_schemes_lock sync.Mutex
+)
+
+// compile_naming_scheme parses a naming scheme, reusing the result of an earlier parse of the same scheme
+func compile_naming_scheme(source string) (*NamingScheme, error) {
+	compiled_schemes_lock.Lock()
+	defer compiled_schemes_lock.Unlock()
+	if scheme, ok := compiled_schemes[source]; ok {
+		return scheme, nil
+	}
+	parse := parse_naming_scheme
+	if strings.HasPrefix(source, template_scheme_prefix) {
+		parse = parse_template_scheme
+	}
+	scheme, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+	compiled_schemes[source] = scheme
+	return scheme, nil
+}
+
+func validate_naming_scheme(s string) error {
+	if _, is_preset, err := find_preset(s); is_preset {
+		return err
+	}
+	_, err := compile_naming_scheme(s)
+	return err
+}
+
+// naming scheme of standalone movies, movies of a set, and movies of a series (--movie-naming-scheme)
+var movie_naming_scheme = some[string]("default")
+
+// tokens that only mean something for episodes
+var episode_token_names = []string{"season_num", "episode_num", "episode_end", "absolute_num", "absolute_end", "special_kind"}
+
+// validate_movie_naming_scheme is validate_naming_scheme for movie naming schemes, which can't have episode tokens
+func validate_movie_naming_scheme(s string) error {
+	if err := validate_naming_scheme(s); err != nil || s == "default" || strings.HasPrefix(s, preset_scheme_prefix) {
+		return err
+	}
+	scheme, _ := compile_naming_scheme(s)
+	for _, token := range episode_token_names {
+		if scheme.uses(token) {
+			return fmt.Errorf("'%s' can't be used in a movie naming scheme", token)
+		}
+	}
+	return nil
+}
+
+// movie_scheme_for is the naming scheme of the movies of an entry with the given naming scheme.
+// without --movie-naming-scheme, movies follow the entry's preset if it has one
+func movie_scheme_for(naming_scheme Option[string]) Option[string] {
+	if ns, _ := movie_naming_scheme.get(); ns == "default" && is_preset_scheme(naming_scheme) {
+		return naming_scheme
+	}
+	return movie_naming_scheme
+}
+
+func parse_naming_scheme(source string) (*NamingScheme, error) {
+	fail := func(pos int, format string, a ...any) error {
+		return &SchemeError{scheme: source, pos: pos, msg: fmt.Sprintf(format, a...)}
+	}
+	nodes, i, err := parse_scheme_nodes(source, 0, fail)
+	if err != nil {
+		return nil, err
+	}
+	if i < len(source) {
+		return nil, fail(i, "unexpected ']' without an opening '[', use '\\]' for a literal ']'")
+	}
+	return &NamingScheme{source: source, nodes: nodes}, nil
+}
+
+// parse_scheme_nodes reads text, tokens, and groups starting at i until the end of the scheme
+// or a ']', and returns the position of where it stopped
+func parse_scheme_nodes(source string, i int, fail func(int, string, ...any) error) ([]SchemeNode, int, error) {
+	nodes := []SchemeNode{}
+	var text strings.Builder
+	text_pos := i
+	flush_text := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, SchemeNode{pos: text_pos, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i < len(source) {
+		switch {
+		case source[i] == '\\' && i+1 < len(source) && strings.IndexByte("[]<", source[i+1]) != -1:
+			if text.Len() == 0 {
+				text_pos = i
+			}
+			text.WriteByte(source[i+1])
+			i += 2
+
+		case source[i] == '[':
+			flush_text()
+			group_pos := i
+			group, next, err := parse_scheme_nodes(source, i+1, fail)
+			if err != nil {
+				return nil, i, err
+			}
+			if next == len(source) {
+				return nil, i, fail(group_pos, "unclosed optional group, missing ']'")
+			}
+			nodes = append(nodes, SchemeNode{pos: group_pos, optional: true, group: group})
+			i = next + 1
+
+		case source[i] == ']':
+			flush_text()
+			return nodes, i, nil
+
+		case source[i] == '<':
+			flush_text()
+			node, next, err := parse_scheme_token(source, i, fail)
+			if err != nil {
+				return nil, i, err
+			}
+			nodes = append(nodes, node)
+			i = next
+
+		default:
+			// a '>' outside a token is text too
+			if text.Len() == 0 {
+				text_pos = i
+			}
+			text.WriteByte(source[i])
+			i++
+		}
+	}
+	flush_text()
+	return nodes, i, nil
+}
+
+// parse_scheme_token reads a token and its fallbacks starting at the '<' at i
+// and returns the position right after its '>'
+func parse_scheme_token(source string, i int, fail func(int, string, ...any) error) (SchemeNode, int, error) {
+	open := i
+	unclosed := func() error {
+		return fail(open, "unclosed token '%s', missing '>'", source[open:])
+	}
+	var node SchemeNode
+	i++
+	for {
+		choice, next, err := parse_scheme_choice(source, i, fail)
+		if err != nil {
+			return node, i, err
+		}
+		if node.token == "" {
+			node = choice
+			node.pos = open
+		} else {
+			node.fallbacks = append(node.fallbacks, choice)
+		}
+		i = next
+		if i == len(source) {
+			return node, i, unclosed()
+		}
+		if strings.HasPrefix(source[i:], "??") {
+			i += 2
+			continue
+		}
+		if source[i] != '>' {
+			return node, i, fail(i, "unexpected '%c' in token '%s', expected '>' or '??'", source[i], source[open:i])
+		}
+		return node, i + 1, nil
+	}
+}
+
+// parse_scheme_choice reads one name and its value in a token, and returns the position after its trailing spaces
+func parse_scheme_choice(source string, i int, fail func(int, string, ...any) error) (SchemeNode, int, error) {
+	node := SchemeNode{pos: i}
+	i = skip_spaces(source, i)
+	name_start := i
+	for i < len(source) && is_name_char(source[i]) {
+		i++
+	}
+	name := source[name_start:i]
+	i = skip_spaces(source, i)
+	if i == len(source) {
+		return node, i, nil
+	}
+	if name == "" {
+		return node, i, fail(name_start, "missing token name")
+	}
+
+	switch {
+	case scheme_number_tokens[name] || is_info_token(name) || name == "self":
+		node.token = name
+	case scheme_parent_long.MatchString(name):
+		node.token = "parent"
+		node.parent = strings.Count(name, "parent")
+	case scheme_parent_short.MatchString(name):
+		node.token = "parent"
+		node.parent = 1
+		if match := scheme_parent_short.FindStringSubmatch(name); match[1] != "" {
+			node.parent, _ = strconv.Atoi(match[1])
+			if node.parent < 1 {
+				return node, i, fail(name_start, "'%s' must go up at least 1 directory, use <self> for the media file", name)
+			}
+		}
+	default:
+		return node, i, fail(name_start, "unknown token '%s'", name)
+	}
+
+	if source[i] == ':' {
+		i = skip_spaces(source, i+1)
+		value, next, err := parse_scheme_value(source, i, name, node.token, fail)
+		if err != nil {
+			return node, i, err
+		}
+		node.value = value
+		i = skip_spaces(source, next)
+	}
+	for i < len(source) && source[i] == '|' {
+		filter, next, err := parse_scheme_filter(source, skip_spaces(source, i+1), fail)
+		if err != nil {
+			return node, i, err
+		}
+		node.filters = append(node.filters, filter)
+		i = skip_spaces(source, next)
+	}
+	return node, i, nil
+}
+
+// parse_scheme_value reads the value of a token starting at i and returns the position right after it
+func parse_scheme_value(source string, i int, name string, token string, fail func(int, string, ...any) error) (SchemeValue, int, error) {
+	value := SchemeValue{pos: i}
+	if i == len(source) || strings.IndexByte(">?|", source[i]) != -1 {
+		return value, i, fail(i, "missing value after '%s:'", name)
+	}
+	if is_info_token(token) {
+		return value, i, fail(i, "'%s' does not take a value", name)
+	}
+
+	// regex: everything up to the closing quote, '>' included
+	if source[i] == '\'' {
+		if scheme_number_tokens[token] {
+			return value, i, fail(i, "'%s' takes a padding number, not a regex", name)
+		}
+		end := strings.IndexByte(source[i+1:], '\'')
+		if end == -1 {
+			return value, i, fail(i, "unclosed regex, missing closing '")
+		}
+		pattern := source[i+1 : i+1+end]
+		if strings.TrimSpace(pattern) == "" {
+			return value, i, fail(i, "regex of '%s' is empty", name)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return value, i, fail(i+1, "invalid regex '%s': %s", pattern, err)
+		}
+		for _, part := range split_regex_by_pipe(pattern) {
+			if !has_only_one_match_group(part) {
+				return value, i, fail(i+1, "regex part '%s' must have exactly one capture group (parts are separated by outermost '|')", part)
+			}
+			value.regexes = append(value.regexes, regexp.MustCompile(part))
+		}
+		value.kind = value_regex
+		return value, i + end + 2, nil
+	}
+
+	// numbers end at the '>', the '??' of a fallback, or the '|' of a filter
+	end := strings.IndexAny(source[i:], ">?|")
+	if end == -1 {
+		end = len(source) - i
+	}
+	raw := strings.TrimSpace(source[i : i+end])
+
+	if scheme_number_tokens[token] {
+		pad, err := strconv.Atoi(raw)
+		if err != nil || pad < 0 || strings.ContainsAny(raw, "+-") {
+			return value, i, fail(i, "padding of '%s' must be a positive integer or 0, not '%s'", name, raw)
+		}
+		value.kind, value.pad = value_pad, pad
+		return value, i + len(raw), nil
+	}
+
+	match := scheme_range.FindStringSubmatch(raw)
+	if match == nil {
+		return value, i, fail(i, "value of '%s' must be <start>,<end> or <index> with positive integers or 0, or a 'regex', not '%s'", name, raw)
+	}
+	value.kind = value_range
+	value.start, _ = strconv.Atoi(match[1])
+	value.end = value.start
+	if match[2] != "" {
+		value.end, _ = strconv.Atoi(match[2])
+	}
+	if value.start > value.end {
+		return value, i, fail(i, "invalid range '%s', start (%d) must not be greater than end (%d)", raw, value.start, value.end)
+	}
+	return value, i + len(raw), nil
+}
+
+// SchemeFilter changes the value of a token after it is resolved, like <parent | upper>.
+// filters of a token are applied in order
+type SchemeFilter struct {
+	pos  int
+	name string
+	args []string
+}
+
+// arguments each filter takes: 'n' for a number and 't' for quoted text
+var scheme_filter_args = map[string]string{
+	"upper":   "",
+	"lower":   "",
+	"title":   "",
+	"trim":    "",
+	"slug":    "",
+	"replace": "tt",
+	"pad":     "n",
+}
+
+// parse_scheme_filter reads a filter starting at i and returns the position right after it
+func parse_scheme_filter(source string, i int, fail func(int, string, ...any) error) (SchemeFilter, int, error) {
+	filter := SchemeFilter{pos: i}
+	for i < len(source) && is_name_char(source[i]) {
+		i++
+	}
+	filter.name = source[filter.pos:i]
+	if filter.name == "" {
+		return filter, i, fail(filter.pos, "missing filter name after '|'")
+	}
+	kinds, ok := scheme_filter_args[filter.name]
+	if !ok {
+		return filter, i, fail(filter.pos, "unknown filter '%s'", filter.name)
+	}
+
+	i = skip_spaces(source, i)
+	if i < len(source) && source[i] == ':' {
+		for {
+			i = skip_spaces(source, i+1)
+			arg_pos := i
+			if i < len(source) && source[i] == '\'' {
+				end := strings.IndexByte(source[i+1:], '\'')
+				if end == -1 {
+					return filter, i, fail(i, "unclosed text, missing closing '")
+				}
+				filter.args = append(filter.args, source[i+1:i+1+end])
+				i += end + 2
+			} else {
+				for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+					i++
+				}
+				if i == arg_pos {
+					return filter, i, fail(i, "filter '%s' takes a number or 'text' after ':'", filter.name)
+				}
+				filter.args = append(filter.args, source[arg_pos:i])
+			}
+			if len(filter.args) <= len(kinds) {
+				is_text := source[arg_pos] == '\''
+				if kinds[len(filter.args)-1] == 'n' && is_text {
+					return filter, i, fail(arg_pos, "filter '%s' takes a number, not text", filter.name)
+				} else if kinds[len(filter.args)-1] == 't' && !is_text {
+					return filter, i, fail(arg_pos, "filter '%s' takes 'text' in single quotes, not a number", filter.name)
+				}
+			}
+			i = skip_spaces(source, i)
+			if i == len(source) || source[i] != ',' {
+				break
+			}
+		}
+	}
+	if len(filter.args) != len(kinds) {
+		return filter, i, fail(filter.pos, "filter '%s' takes %d arguments, got %d", filter.name, len(kinds), len(filter.args))
+	}
+	return filter, i, nil
+}
+
+func (filter SchemeFilter) apply(value string) string {
+	switch filter.name {
+	case "upper":
+		return strings.ToUpper(value)
+	case "lower":
+		return strings.ToLower(value)
+	case "title":
+		return title_case(value)
+	case "trim":
+		return strings.TrimSpace(value)
+	case "slug":
+		return slugify(value)
+	case "replace":
+		return strings.ReplaceAll(value, filter.args[0], filter.args[1])
+	case "pad":
+		// only numbers are padded. text is left as is
+		num, err := strconv.Atoi(value)
+		if err != nil || num < 0 {
+			return value
+		}
+		pad, _ := strconv.Atoi(filter.args[0])
+		return fmt.Sprintf("%0*d", pad, num)
+	}
+	return value
+}
+
+// title_case uppercases the first letter of every word and lowercases the rest
+func title_case(s string) string {
+	var title strings.Builder
+	word_start := true
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' {
+			if word_start {
+				title.WriteRune(unicode.ToUpper(r))
+			} else {
+				title.WriteRune(unicode.ToLower(r))
+			}
+			word_start = false
+		} else {
+			title.WriteRune(r)
+			word_start = true
+		}
+	}
+	return title.String()
+}
+
+// slugify lowercases s and joins its words with '-', like "Fruits Basket (2019)" --> "fruits-basket-2019"
+func slugify(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+func skip_spaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func is_name_char(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// uses reports whether any of the tokens is in the scheme, including in groups and fallbacks
+func (scheme *NamingScheme) uses(tokens ...string) bool {
+	if scheme.template != nil {
+		for _, token := range tokens {
+			if strings.Contains(scheme.source, token) {
+				return true
+			}
+		}
+		return false
+	}
+	return nodes_use(scheme.nodes, tokens)
+}
+
+func nodes_use(nodes []SchemeNode, tokens []string) bool {
+	for _, node := range nodes {
+		for _, token := range tokens {
+			if node.token == token {
+				return true
+			}
+		}
+		if nodes_use(node.fallbacks, tokens) || nodes_use(node.group, tokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemeContext is everything about a media file a naming scheme can be rendered with
+type SchemeContext struct {
+	season_pad int
+	season_num int
+	ep_pad     int
+	ep_num     int
+	abs_path   string
+	// title of the default naming scheme
+	title string
+	// episode number counted from the first episode of the first season
+	abs_num int
+	// movies read their year, edition, and provider ids from the name of their directory too.
+	// collection is the movie set or series the movie is in
+	movie      bool
+	collection string
+	// the series directory episodes read their year and provider ids from
+	entry string
+}
+
+// render builds the new name of a media file, without its extension
+func (scheme *NamingScheme) render(ctx SchemeContext) (string, error) {
+	release := parse_release_name(filepath.Base(ctx.abs_path))
+	ep_end := ctx.ep_num
+	if release.is_multi_episode() {
+		ep_end = ctx.ep_num + len(release.episodes) - 1
+	}
+
+	// release tokens (<resolution>, <codec>, <group>, etc.) and probe tokens (<video_res>, <video_codec>, <audio>, etc.)
+	tokens := release.tokens()
+	tokens["title"] = ctx.title
+	tokens["collection"] = ctx.collection
+	tags := MediaTags{year: -1}
+	if ctx.movie {
+		tags = movie_media_tags(filepath.Dir(ctx.abs_path), ctx.abs_path)
+	} else if ctx.entry != "" {
+		tags = parse_media_tags(filepath.Base(ctx.entry))
+	}
+	if tokens["year"] == "" && tags.year != -1 {
+		tokens["year"] = strconv.Itoa(tags.year)
+	}
+	if tokens["edition"] == "" {
+		tokens["edition"] = tags.edition
+	}
+	for token, value := range tags.tokens() {
+		tokens[token] = value
+	}
+	// movies of a set are usually split by their directory like "Kill Bill Part 2 (2004)"
+	if dir := parse_release_name(filepath.Base(filepath.Dir(ctx.abs_path))); ctx.movie && tokens["part"] == "" && dir.part > 0 {
+		tokens["part"] = strconv.Itoa(dir.part)
+	}
+	tokens["special_kind"] = ""
+	if ctx.season_num == 0 {
+		tokens["special_kind"] = special_kind_of_path(ctx.abs_path)
+	}
+	if scheme.uses(probe_token_names...) {
+		stream, err := probe_media_file(ctx.abs_path)
+		if err != nil {
+			warn("%s", err)
+		}
+		for token, value := range stream.tokens() {
+			// stream properties are more accurate than the release name but the release name is better than nothing
+			if value == "" {
+				value = tokens[probe_fallback_tokens[token]]
+			}
+			tokens[token] = value
+		}
+	}
+
+	if scheme.template != nil {
+		return scheme.render_template(ctx, ep_end, tokens)
+	}
+
+	resolve := func(node SchemeNode) (string, bool) {
+		switch node.token {
+		case "season_num":
+			return node.value.pad_number(ctx.season_num, ctx.season_pad), true
+		case "episode_num":
+			return node.value.pad_number(ctx.ep_num, ctx.ep_pad), true
+		case "episode_end":
+			return node.value.pad_number(ep_end, ctx.ep_pad), true
+		case "absolute_num":
+			return node.value.pad_number(ctx.abs_num, ctx.ep_pad), true
+		case "absolute_end":
+			return node.value.pad_number(ctx.abs_num+ep_end-ctx.ep_num, ctx.ep_pad), true
+		case "self":
+			base := filepath.Base(ctx.abs_path)
+			return node.value.select_from(strings.TrimSuffix(base, filepath.Ext(base)))
+		case "parent":
+			return node.value.select_from(nth_parent(ctx.abs_path, node.parent))
+		default:
+			return tokens[node.token], tokens[node.token] != ""
+		}
+	}
+	name, _ := render_nodes(scheme.nodes, resolve)
+	// filters and tokens can put path separators in the name which would move the file into other directories
+	if strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("naming scheme for %s made '%s' which has a path separator", ctx.abs_path, name)
+	}
+	return name, nil
+}
+
+// render_nodes joins the rendered nodes and reports whether all tokens directly in them resolved.
+// a group that has a token that didn't resolve is left out
+func render_nodes(nodes []SchemeNode, resolve func(SchemeNode) (string, bool)) (string, bool) {
+	var name strings.Builder
+	all_resolved := true
+	for _, node := range nodes {
+		switch {
+		case node.optional:
+			if group, ok := render_nodes(node.group, resolve); ok {
+				name.WriteString(group)
+			}
+		case node.token == "":
+			name.WriteString(node.text)
+		default:
+			value, ok := resolve_filtered(node, resolve)
+			for _, fallback := range node.fallbacks {
+				if ok {
+					break
+				}
+				value, ok = resolve_filtered(fallback, resolve)
+			}
+			all_resolved = all_resolved && ok
+			name.WriteString(value)
+		}
+	}
+	return name.String(), all_resolved
+}
+
+// resolve_filtered resolves a token and applies its filters. a value that is empty after its filters is not resolved
+func resolve_filtered(node SchemeNode, resolve func(SchemeNode) (string, bool)) (string, bool) {
+	value, ok := resolve(node)
+	for _, filter := range node.filters {
+		value = filter.apply(value)
+	}
+	return value, ok && value != ""
+}
+
+func (value SchemeValue) pad_number(num int, default_pad int) string {
+	if value.kind == value_pad {
+		return fmt.Sprintf("%0*d", value.pad, num)
+	}
+	return fmt.Sprintf("%0*d", default_pad, num)
+}
+
+// select_from picks the characters of a range or the capture group of a regex from a name.
+// a range past the end of the name stops at its last character. if nothing can be picked, the whole name is used
+// and it is reported as not resolved so groups and fallbacks can skip it
+func (value SchemeValue) select_from(name string) (string, bool) {
+	switch value.kind {
+	case value_range:
+		if value.start >= len(name) {
+			return name, false
+		}
+		return name[value.start:min(value.end+1, len(name))], true
+	case value_regex:
+		for _, re := range value.regexes {
+			if match := re.FindStringSubmatch(name); len(match) > 1 {
+				return match[1], match[1] != ""
+			}
+		}
+		return name, false
+	}
+	return name, name != ""
+}
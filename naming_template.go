@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// a naming scheme starting with this is a go text/template like
+//
+//	tmpl:S{{pad 2 .Season}}E{{pad 2 .Episode}}{{with .Info.episode_title}} - {{.}}{{end}}
+const template_scheme_prefix = "tmpl:"
+
+// TemplateData is what a template naming scheme is executed with
+type TemplateData struct {
+	// title of the default naming scheme, usually the series or movie directory name
+	Title      string
+	Season     int
+	Episode    int
+	EpisodeEnd int
+	Absolute   int
+	SeasonPad  int
+	EpisodePad int
+	// name of the media file without its extension, and its extension with the dot
+	Self string
+	Ext  string
+	// names of the directories above the media file. Parents 0 is the directory the file is in
+	Parents []string
+	// release tokens, stream tokens, and tokens of the entry like special_kind by the names they have in <> naming schemes
+	Info    map[string]string
+	Size    int64
+	ModTime time.Time
+}
+
+// only functions that work on values are available. nothing that can touch files or the environment
+var template_scheme_funcs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"title":   title_case,
+	"trim":    strings.TrimSpace,
+	"slug":    slugify,
+	"replace": func(from string, to string, s string) string { return strings.ReplaceAll(s, from, to) },
+	"pad": func(pad int, value any) string {
+		num, err := strconv.Atoi(fmt.Sprint(value))
+		if err != nil || num < 0 {
+			return fmt.Sprint(value)
+		}
+		return fmt.Sprintf("%0*d", pad, num)
+	},
+	// default returns fallback if value is empty, like the ?? of <> naming schemes
+	"default": func(fallback string, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	// match returns the first capture group of a regex in s, or "" if it doesn't match
+	"match": func(pattern string, s string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		if match := re.FindStringSubmatch(s); len(match) > 1 {
+			return match[1], nil
+		}
+		return "", nil
+	},
+}
+
+func parse_template_scheme(source string) (*NamingScheme, error) {
+	tmpl, err := template.New("naming scheme").Funcs(template_scheme_funcs).Option("missingkey=error").Parse(strings.TrimPrefix(source, template_scheme_prefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template naming scheme '%s': %s", source, strings.TrimPrefix(err.Error(), "template: "))
+	}
+	// a dry run catches fields and info tokens that don't exist before any file is renamed with it
+	sample := TemplateData{Parents: make([]string, 16), Info: map[string]string{}}
+	for _, names := range [][]string{release_token_names, probe_token_names, context_token_names} {
+		for _, name := range names {
+			sample.Info[name] = ""
+		}
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("invalid template naming scheme '%s': %s", source, strings.TrimPrefix(err.Error(), "template: "))
+	}
+	return &NamingScheme{source: source, template: tmpl}, nil
+}
+
+func (scheme *NamingScheme) render_template(ctx SchemeContext, ep_end int, tokens map[string]string) (string, error) {
+	base := filepath.Base(ctx.abs_path)
+	data := TemplateData{
+		Title:      ctx.title,
+		Season:     ctx.season_num,
+		Episode:    ctx.ep_num,
+		EpisodeEnd: ep_end,
+		Absolute:   ctx.abs_num,
+		SeasonPad:  ctx.season_pad,
+		EpisodePad: ctx.ep_pad,
+		Self:       strings.TrimSuffix(base, filepath.Ext(base)),
+		Ext:        filepath.Ext(base),
+		Info:       tokens,
+	}
+	for dir := filepath.Dir(ctx.abs_path); filepath.Dir(dir) != dir; dir = filepath.Dir(dir) {
+		data.Parents = append(data.Parents, filepath.Base(dir))
+	}
+	if info, err := os.Stat(ctx.abs_path); err == nil {
+		data.Size = info.Size()
+		data.ModTime = info.ModTime()
+	}
+
+	var name strings.Builder
+	if err := scheme.template.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("naming scheme failed for %s: %s", ctx.abs_path, strings.TrimPrefix(err.Error(), "template: "))
+	}
+	if strings.ContainsAny(name.String(), `/\`) {
+		return "", fmt.Errorf("naming scheme for %s made '%s' which has a path separator", ctx.abs_path, name.String())
+	}
+	return name.String(), nil
+}
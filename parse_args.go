@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"os"
@@ -14,6 +13,26 @@ type Args struct {
 	series          	[]string
 	movies          	[]string
 	options 	AdditionalOptions
+	tui             	bool
+	answers         	string
+	record_answers  	string
+	type_overrides  	[]TypeOverride
+	explain         	bool
+	rules           	string
+	specials_order  	[]string
+	specials_range  	int
+	depth           	int
+	aliases         	map[string]string
+	categories      	[]string
+	entry_filters   	[]Filter
+	file_filters    	[]Filter
+	state           	string
+	jobs            	int
+	link            	string
+	dest            	string
+	copy            	bool
+	movie_naming_scheme	Option[string]
+	tag_style       	Option[string]
 }
 type AdditionalOptions struct {
 	keep_ep_nums    Option[bool]
@@ -26,6 +45,11 @@ func new_Args() Args {
 		root:            make([]string, 0),
 		series:          make([]string, 0),
 		movies:          make([]string, 0),
+		depth:           1,
+		jobs:            1,
+		aliases:         make(map[string]string),
+		movie_naming_scheme: none[string](),
+		tag_style:       none[string](),
 		options: AdditionalOptions{
 			has_season_0:    none[bool](),
 			keep_ep_nums:    none[bool](),
@@ -99,6 +123,212 @@ func parse_args(args []string) (Args, error) {
 			}
 			skip_iter = i + 1
 
+		} else if arg == "--tui" {
+			parsed_args.tui = true
+
+		} else if arg == "--explain" {
+			parsed_args.explain = true
+
+		} else if arg == "--type" || arg == "-t" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing '<glob>=<type>' value for flag '%s'", arg)
+			}
+			sep := strings.LastIndex(args[i+1], "=")
+			if sep <= 0 {
+				return Args{}, fmt.Errorf("invalid value '%s' for flag '%s'. Must be '<glob>=<type>'", args[i+1], arg)
+			}
+			pattern, kind := args[i+1][:sep], strings.TrimSpace(args[i+1][sep+1:])
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return Args{}, fmt.Errorf("invalid glob '%s' for flag '%s': %s", pattern, arg, err)
+			}
+			parsed_args.type_overrides = append(parsed_args.type_overrides, TypeOverride{pattern: pattern, kind: kind})
+			skip_iter = i + 1
+
+		} else if arg == "--rules" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing file path value for flag '%s'", arg)
+			} else if parsed_args.rules != "" {
+				return Args{}, fmt.Errorf("only one --rules flag is allowed")
+			}
+			file, err := filepath.Abs(args[i+1])
+			if err != nil {
+				return Args{}, err
+			}
+			if _, err := os.Stat(file); err != nil {
+				return Args{}, fmt.Errorf("rules file %s does not exist", file)
+			}
+			parsed_args.rules = file
+			skip_iter = i + 1
+
+		} else if arg == "--copy" {
+			parsed_args.copy = true
+
+		} else if arg == "--dest" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing dir path value for flag '%s'", arg)
+			} else if parsed_args.dest != "" {
+				return Args{}, fmt.Errorf("only one --dest flag is allowed")
+			}
+			dir, err := filepath.Abs(args[i+1])
+			if err != nil {
+				return Args{}, err
+			}
+			parsed_args.dest = dir
+			skip_iter = i + 1
+
+		} else if arg == "--link" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing dir path value for flag '%s'", arg)
+			} else if parsed_args.link != "" {
+				return Args{}, fmt.Errorf("only one --link flag is allowed")
+			}
+			dir, err := filepath.Abs(args[i+1])
+			if err != nil {
+				return Args{}, err
+			}
+			parsed_args.link = dir
+			skip_iter = i + 1
+
+		} else if arg == "--state" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing file path value for flag '%s'", arg)
+			} else if parsed_args.state != "" {
+				return Args{}, fmt.Errorf("only one --state flag is allowed")
+			}
+			file, err := filepath.Abs(args[i+1])
+			if err != nil {
+				return Args{}, err
+			}
+			parsed_args.state = file
+			skip_iter = i + 1
+
+		} else if arg == "--movie-naming-scheme" || arg == "-mns" {
+			if parsed_args.movie_naming_scheme.is_some() {
+				return Args{}, fmt.Errorf("only one --movie-naming-scheme flag is allowed")
+			} else if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing value for --movie-naming-scheme")
+			}
+			if err := validate_movie_naming_scheme(args[i+1]); err != nil {
+				return Args{}, err
+			}
+			parsed_args.movie_naming_scheme = some[string](args[i+1])
+			skip_iter = i + 1
+
+		} else if arg == "--tag-style" || arg == "-ts" {
+			if parsed_args.tag_style.is_some() {
+				return Args{}, fmt.Errorf("only one --tag-style flag is allowed")
+			} else if len(args) <= i+1 {
+				return Args{}, fmt.Errorf("missing value for --tag-style")
+			}
+			if err := validate_media_tag_style(args[i+1]); err != nil {
+				return Args{}, err
+			}
+			parsed_args.tag_style = some[string](args[i+1])
+			skip_iter = i + 1
+
+		} else if arg == "--jobs" || arg == "-j" {
+			if len(args) <= i+1 {
+				return Args{}, fmt.Errorf("missing number value for flag '%s'", arg)
+			}
+			count, err := strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {
+				return Args{}, fmt.Errorf("invalid value '%s' for flag '%s'. Must be a positive number", args[i+1], arg)
+			}
+			parsed_args.jobs = count
+			skip_iter = i + 1
+
+		} else if arg == "--depth" {
+			if len(args) <= i+1 {
+				return Args{}, fmt.Errorf("missing number value for flag '%s'", arg)
+			}
+			depth, err := strconv.Atoi(args[i+1])
+			if err != nil || depth < 1 {
+				return Args{}, fmt.Errorf("invalid value '%s' for flag '%s'. Must be a positive number", args[i+1], arg)
+			}
+			parsed_args.depth = depth
+			skip_iter = i + 1
+
+		} else if arg == "--alias" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing '<name>=series|movies' value for flag '%s'", arg)
+			}
+			sep := strings.LastIndex(args[i+1], "=")
+			if sep <= 0 {
+				return Args{}, fmt.Errorf("invalid value '%s' for flag '%s'. Must be '<name>=series' or '<name>=movies'", args[i+1], arg)
+			}
+			name, media := strings.TrimSpace(args[i+1][:sep]), strings.ToLower(strings.TrimSpace(args[i+1][sep+1:]))
+			if media != "series" && media != "movies" {
+				return Args{}, fmt.Errorf("invalid value '%s' for flag '%s'. Must be '<name>=series' or '<name>=movies'", args[i+1], arg)
+			}
+			parsed_args.aliases[name] = media
+			skip_iter = i + 1
+
+		} else if arg == "--category" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing directory name value for flag '%s'", arg)
+			}
+			parsed_args.categories = append(parsed_args.categories, args[i+1])
+			skip_iter = i + 1
+
+		} else if arg == "--include" || arg == "--exclude" || arg == "--include-files" || arg == "--exclude-files" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing '<glob>' or 're:<regex>' value for flag '%s'", arg)
+			}
+			filter, err := new_filter(args[i+1], strings.HasPrefix(arg, "--exclude"))
+			if err != nil {
+				return Args{}, fmt.Errorf("%s for flag '%s'", err, arg)
+			}
+			if strings.HasSuffix(arg, "-files") {
+				parsed_args.file_filters = append(parsed_args.file_filters, filter)
+			} else {
+				parsed_args.entry_filters = append(parsed_args.entry_filters, filter)
+			}
+			skip_iter = i + 1
+
+		} else if arg == "--specials-order" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing comma separated specials kinds for flag '%s'", arg)
+			} else if len(parsed_args.specials_order) > 0 {
+				return Args{}, fmt.Errorf("only one --specials-order flag is allowed")
+			}
+			parsed_args.specials_order = strings.Split(args[i+1], ",")
+			skip_iter = i + 1
+
+		} else if arg == "--specials-range" {
+			if len(args) <= i+1 {
+				return Args{}, fmt.Errorf("missing number value for flag '%s'", arg)
+			}
+			size, err := strconv.Atoi(args[i+1])
+			if err != nil || size < 1 {
+				return Args{}, fmt.Errorf("invalid value '%s' for flag '%s'. Must be a positive number", args[i+1], arg)
+			}
+			parsed_args.specials_range = size
+			skip_iter = i + 1
+
+		} else if arg == "--answers" || arg == "--record-answers" {
+			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
+				return Args{}, fmt.Errorf("missing file path value for flag '%s'", arg)
+			}
+			file, err := filepath.Abs(args[i+1])
+			if err != nil {
+				return Args{}, err
+			}
+
+			if arg == "--answers" {
+				if parsed_args.answers != "" {
+					return Args{}, fmt.Errorf("only one --answers flag is allowed")
+				} else if _, err := os.Stat(file); err != nil {
+					return Args{}, fmt.Errorf("answers file %s does not exist", file)
+				}
+				parsed_args.answers = file
+			} else {
+				if parsed_args.record_answers != "" {
+					return Args{}, fmt.Errorf("only one --record-answers flag is allowed")
+				}
+				parsed_args.record_answers = file
+			}
+			skip_iter = i + 1
+
 		} else if arg == "--season-0" || arg == "-s0" {
 			if parsed_args.options.has_season_0.is_some() {
 				return Args{}, fmt.Errorf("only one --season-0 flag is allowed")
@@ -226,8 +456,16 @@ func parse_args(args []string) (Args, error) {
 			if len(args) <= i+1 || (len(args) > i+1 && args[i+1][0] == '-') {
 				return Args{}, fmt.Errorf("missing value for --naming-scheme")
 
+			} else if strings.HasPrefix(args[i+1], preset_scheme_prefix) {
+				// a preset is a naming scheme for everything so 'all' is optional
+				if _, _, err := find_preset(args[i+1]); err != nil {
+					return Args{}, err
+				}
+				parsed_args.options.naming_scheme = some[string](args[i+1])
+				skip_iter = i + 1
+
 			} else if args[i+1] != "all" && args[i+1] != "var" {
-				return Args{}, fmt.Errorf("invalid value '%s' for --naming-scheme. Must be 'all' or 'var", args[i+1])
+				return Args{}, fmt.Errorf("invalid value '%s' for --naming-scheme. Must be 'all', 'var', or 'preset:<name>'", args[i+1])
 			
 			} else if args[i+1] == "all" {
 				if len(args) < i+2 || args[i+2][0] == '-' {
@@ -254,153 +492,80 @@ func parse_args(args []string) (Args, error) {
 	if err != nil {
 		return Args{}, err
 	}
-
-	if !assigned["--options"] {
-		// use default values for additional options
-		if parsed_args.options.has_season_0.is_none() {
-			parsed_args.options.has_season_0 = some[bool](false)
-		}
-		if parsed_args.options.keep_ep_nums.is_none() {
-			parsed_args.options.keep_ep_nums = some[bool](false)
-		}
-		if parsed_args.options.starting_ep_num.is_none() {
-			parsed_args.options.starting_ep_num = some[int](1)
+	if parsed_args.link != "" && parsed_args.dest != "" {
+		return Args{}, fmt.Errorf("only one of --link and --dest is allowed")
+	} else if parsed_args.copy && parsed_args.dest == "" {
+		return Args{}, fmt.Errorf("--copy needs a --dest directory to copy to")
+	}
+	// the library would be renamed again in later runs if it was under a root
+	for flag, library := range map[string]string{"--link": parsed_args.link, "--dest": parsed_args.dest} {
+		if library == "" {
+			continue
 		}
-		if parsed_args.options.naming_scheme.is_none() {
-			parsed_args.options.naming_scheme = some[string]("default")
+		for _, dir := range append(append(append([]string{}, parsed_args.root...), parsed_args.series...), parsed_args.movies...) {
+			if rel, err := filepath.Rel(dir, library); err == nil && !strings.HasPrefix(rel, "..") {
+				return Args{}, fmt.Errorf("%s directory %s must not be inside %s", flag, library, dir)
+			}
 		}
 	}
-	return parsed_args, nil
-}
 
-func validate_naming_scheme(s string) error {
-	tokens, err := tokenize_naming_scheme(s)
-	if err != nil {
-		return err
-	}
-
-	valid_api := regexp.MustCompile(`^season_num$|^episode_num$|^self$`)
-	valid_parent_api := regexp.MustCompile(`^parent(-parent)*$|^p(-\d+)?$`)
-	valid_range := regexp.MustCompile(`^\d+(\s*,\s*\d+)?$`)
-
-	for _,token := range tokens {
-		var api, val string
-
-		// for api with values
-		if strings.Contains(token, ":") {
-			res := strings.SplitN(token, ":", 2)
-			api, val = strings.TrimSpace(res[0]), strings.TrimSpace(res[1])
-		} else {
-			api, val = strings.TrimSpace(token), "none"
-		}
-
-		if !valid_api.MatchString(api) && !valid_parent_api.MatchString(api) {
-			return fmt.Errorf("invalid api: %s", api)
+	// types are checked after the rules file is loaded since it can add new ones
+	if parsed_args.rules != "" {
+		if err := load_rules(parsed_args.rules); err != nil {
+			return Args{}, err
 		}
-
-		if api == "season_num" || api ==  "episode_num" {
-			if val == "none" {
-				continue
-			}
-			if val == "" {
-				return fmt.Errorf("season_num's value cannot be empty and must be a positive integer")
-			}
-
-			int_val, err := strconv.Atoi(val)
-			if err != nil {
-				return fmt.Errorf("%s's value must be a positive integer. '%s' is not a valid integer or 0", api, val)
-			}
-			if int_val < 0 {
-				return fmt.Errorf("%s's value must be a positive integer. '%s' is not a positive integer or 0", api, val)
-			}
-
-		} else if api == "self" || valid_parent_api.MatchString(api) {
-			if val == "none" {
-				continue
-			}
-			if val == "" {
-				return fmt.Errorf("%s's value cannot be empty", api)
-			}
-			if val[0] == '\'' {
-				single_closed_string := regexp.MustCompile(`^'[^']*'$`)
-				if !single_closed_string.MatchString(val) {
-					return fmt.Errorf("%s is either an unclosed string or contains more than 2 single quotes", val)
-				}
-				
-				val_trimmed := strings.Trim(val, "'")
-				just_whitespace := regexp.MustCompile(`^\s*$`)
-				if just_whitespace.MatchString(val_trimmed) {
-					return fmt.Errorf("%s is an empty string (just whitespace/s)", val)
-				}
-
-				if _, err := regexp.Compile(val_trimmed); err != nil {
-					return fmt.Errorf("invalid regex: %s", val)
-
-				} else {
-					parts := split_regex_by_pipe(val_trimmed)
-					for _, part := range parts {
-						if !has_only_one_match_group(part) {
-							return fmt.Errorf("regex should have only one match group per part (parts are separated by outermost pipes |): %s", val)
-						}
-					}
-					// valid regex
-					continue
-				}
-
-			} else if !valid_range.MatchString(val) {
-				return fmt.Errorf("%s's value must be in the format <start>,<end> where <start> and <end> are positive integers and 0. '%s' is not a valid range", api, val)
-			}
-
-			// valid range
-			var res []string
-			if strings.Contains(val, ",") {
-				res = strings.SplitN(val, ",", 2)
-			} else {
-				res = []string{val, val}
-			}
-			begin, end := strings.TrimSpace(res[0]), strings.TrimSpace(res[1])
-			if begin > end {
-				return fmt.Errorf("%s is an invalid range. begin (%s) must be less than or equal to end (%s)", val, begin, end)
-			}
+	}
+	if err := set_specials_order(parsed_args.specials_order); err != nil {
+		return Args{}, err
+	}
+	specials_range = parsed_args.specials_range
+	set_discovery(parsed_args.depth, parsed_args.aliases, parsed_args.categories)
+	set_filters(parsed_args.entry_filters, parsed_args.file_filters)
+	jobs = parsed_args.jobs
+	dest_root, dest_copy = parsed_args.dest, parsed_args.copy
+	for _, override := range parsed_args.type_overrides {
+		if find_rule(series_rules, override.kind) == nil && find_rule(movie_rules, override.kind) == nil {
+			return Args{}, fmt.Errorf("invalid type '%s' for flag '--type'. Must be a series type (%s) or a movie type (%s)",
+				override.kind, strings.Join(rule_names(series_rules), ", "), strings.Join(rule_names(movie_rules), ", "))
 		}
 	}
 
-	return nil
+	if !assigned["--options"] {
+		// use default values for additional options
+		parsed_args.options = parsed_args.options.with_defaults()
+	}
+	// movies follow the preset of --naming-scheme unless they have their own naming scheme
+	movie_naming_scheme = parsed_args.movie_naming_scheme
+	if movie_naming_scheme.is_none() && is_preset_scheme(parsed_args.options.naming_scheme) {
+		movie_naming_scheme = parsed_args.options.naming_scheme
+	} else if movie_naming_scheme.is_none() {
+		movie_naming_scheme = some[string]("default")
+	}
+	media_tag_style = parsed_args.tag_style
+	return parsed_args, nil
 }
 
-func tokenize_naming_scheme(s string) ([]string, error) {
-	is_token := false
-	builder := strings.Builder{}
-	naming_scheme := make([]string, 0)
-
-	for i, c := range s {
-		if is_token && i+1 == len(s) && c != '>' {
-		    return nil, fmt.Errorf("reached end of string but still in an unclosed api: '<%s%s'", builder.String(), string(c))
-		}
-
-		// start of token
-		if c == '<' && !is_token {
-			is_token = true
-			builder.Reset()
-			continue
-
-		// end of token
-		} else if c == '>' && is_token {
-			is_token = false
-			naming_scheme = append(naming_scheme, builder.String())
-			builder.Reset()
-			continue
-		}
-
-		if is_token {
-			_, err := builder.WriteRune(c)
-			if err != nil {
-				return nil, err
-			}
-		}
+// with_defaults fills every option left as var (none) with its default value
+func (options AdditionalOptions) with_defaults() AdditionalOptions {
+	if options.has_season_0.is_none() {
+		options.has_season_0 = some[bool](false)
+	}
+	if options.keep_ep_nums.is_none() {
+		options.keep_ep_nums = some[bool](false)
+	}
+	if options.starting_ep_num.is_none() {
+		options.starting_ep_num = some[int](1)
 	}
+	if options.naming_scheme.is_none() {
+		options.naming_scheme = some[string]("default")
+	}
+	return options
+}
 
-	return naming_scheme, nil
+// has_var reports whether any option is left as var (none), so entries or their seasons are prompted for it
+func (options AdditionalOptions) has_var() bool {
+	return options.has_season_0.is_none() || options.keep_ep_nums.is_none() ||
+		options.starting_ep_num.is_none() || options.naming_scheme.is_none()
 }
 
 func validate_roots(root []string, series []string, movies []string) error {
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// a naming scheme of "preset:<name>" uses the naming schemes of a built in preset
+const preset_scheme_prefix = "preset:"
+
+// NamingPreset is a set of naming schemes that follow the naming conventions of a media server.
+// movies of every preset are named "<title> (<year>)" followed by their edition and provider ids in the
+// preset's tag style
+type NamingPreset struct {
+	episode       string
+	multi_episode string
+	special       string
+	tag_style     string
+}
+
+var naming_presets = map[string]NamingPreset{
+	// https://support.plex.tv/articles/naming-and-organizing-your-tv-show-files/
+	"plex": {
+		episode:       "<title> - s<season_num>e<episode_num>[ - <episode_title>]",
+		multi_episode: "<title> - s<season_num>e<episode_num>-e<episode_end>[ - <episode_title>]",
+		special:       "<title> - s<season_num>e<episode_num>[ - <episode_title>]",
+		tag_style:     "plex",
+	},
+	// https://jellyfin.org/docs/general/server/media/shows
+	"jellyfin": {
+		episode:       "<title> S<season_num>E<episode_num>[ - <episode_title>]",
+		multi_episode: "<title> S<season_num>E<episode_num>-E<episode_end>[ - <episode_title>]",
+		special:       "<title> S<season_num>E<episode_num>[ - <episode_title>]",
+		tag_style:     "jellyfin",
+	},
+	// https://kodi.wiki/view/Naming_video_files/TV_shows
+	"kodi": {
+		episode:       "<title> S<season_num>E<episode_num>[ <episode_title>]",
+		multi_episode: "<title> S<season_num>E<episode_num>-E<episode_end>[ <episode_title>]",
+		special:       "<title> S<season_num>E<episode_num>[ <episode_title>]",
+		tag_style:     "none",
+	},
+	// https://emby.media/support/articles/TV-Naming.html
+	"emby": {
+		episode:       "<title> - S<season_num>E<episode_num>[ - <episode_title>]",
+		multi_episode: "<title> - S<season_num>E<episode_num>-E<episode_end>[ - <episode_title>]",
+		special:       "<title> - S<season_num>E<episode_num>[ - <episode_title>]",
+		tag_style:     "emby",
+	},
+	// episodes numbered from the first episode of the first season, like most anime releases.
+	// specials keep their own numbering since they are not part of it
+	"anime-absolute": {
+		episode:       "<title> - <absolute_num: 3>[ - <episode_title>]",
+		multi_episode: "<title> - <absolute_num: 3>-<absolute_end: 3>[ - <episode_title>]",
+		special:       "<title> - S<season_num>E<episode_num>[ - <episode_title>]",
+		tag_style:     "jellyfin",
+	},
+}
+
+func preset_names() []string {
+	names := make([]string, 0, len(naming_presets))
+	for name := range naming_presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// find_preset returns the preset of a "preset:<name>" naming scheme. ok is false if the scheme is not a preset
+func find_preset(scheme string) (preset NamingPreset, ok bool, err error) {
+	if !strings.HasPrefix(scheme, preset_scheme_prefix) {
+		return NamingPreset{}, false, nil
+	}
+	name := strings.TrimPrefix(scheme, preset_scheme_prefix)
+	preset, ok = naming_presets[name]
+	if !ok {
+		return NamingPreset{}, true, fmt.Errorf("unknown naming preset '%s'. Must be one of %s", name, strings.Join(preset_names(), ", "))
+	}
+	return preset, true, nil
+}
+
+// scheme_for picks the naming scheme of the preset for a media file
+func (preset NamingPreset) scheme_for(season_num int, abs_path string) string {
+	if season_num == 0 {
+		return preset.special
+	}
+	if parse_release_name(filepath.Base(abs_path)).is_multi_episode() {
+		return preset.multi_episode
+	}
+	return preset.episode
+}
+
+// is_preset_scheme reports whether a naming scheme option is a preset
+func is_preset_scheme(naming_scheme Option[string]) bool {
+	ns, err := naming_scheme.get()
+	return err == nil && strings.HasPrefix(ns, preset_scheme_prefix)
+}
+
+// preset_movie_name names a movie "<title> (<year>)" like every preset does, followed by its edition and
+// provider ids in a tag style. these are read from the movie directory's name first then the media file's
+func preset_movie_name(title string, dir string, file string, style string) string {
+	return movie_media_tags(dir, file).tagged_name(title, style)
+}
@@ -0,0 +1,813 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stream properties read from the headers of a media file.
+// only the first video and first audio track are considered
+type StreamInfo struct {
+	width          int
+	height         int
+	video_codec    string
+	hdr            string
+	audio_codec    string
+	audio_channels int
+	duration       time.Duration
+}
+
+// tokens exposed to naming schemes from probing the media file. all of them take no value
+var probe_token_names = []string{
+	"video_res",
+	"video_codec",
+	"hdr",
+	"audio",
+	"audio_codec",
+	"audio_channels",
+	"duration",
+}
+
+// the release token a probe token falls back to when it could not be read from the file
+var probe_fallback_tokens = map[string]string{
+	"video_res":   "resolution",
+	"video_codec": "codec",
+	"audio":       "audio",
+}
+
+var err_unsupported_container = errors.New("unsupported container")
+
+// probe_media_file reads stream properties from matroska (.mkv, .webm) and mp4 (.mp4, .mov) headers
+func probe_media_file(path string) (StreamInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 12)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return StreamInfo{}, fmt.Errorf("could not read header of %s: %w", filepath.Base(path), err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return StreamInfo{}, err
+	}
+
+	var info StreamInfo
+	if binary.BigEndian.Uint32(magic) == mkv_ebml {
+		err = probe_matroska(file, &info)
+	} else if string(magic[4:8]) == "ftyp" || string(magic[4:8]) == "moov" || string(magic[4:8]) == "free" || string(magic[4:8]) == "mdat" || string(magic[4:8]) == "wide" {
+		err = probe_mp4(file, &info)
+	} else {
+		err = err_unsupported_container
+	}
+	if err != nil {
+		return StreamInfo{}, fmt.Errorf("could not probe %s: %w", filepath.Base(path), err)
+	}
+	return info, nil
+}
+
+// tokens returns the value of every probe token for use in naming schemes
+func (info StreamInfo) tokens() map[string]string {
+	channels := channel_layout(info.audio_channels)
+	audio := strings.TrimSpace(info.audio_codec + " " + channels)
+
+	duration := ""
+	if info.duration > 0 {
+		minutes := int(info.duration.Round(time.Minute).Minutes())
+		if minutes >= 60 {
+			duration = fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+		} else {
+			duration = fmt.Sprintf("%dm", minutes)
+		}
+	}
+
+	return map[string]string{
+		"video_res":      video_resolution(info.width, info.height),
+		"video_codec":    info.video_codec,
+		"hdr":            info.hdr,
+		"audio":          audio,
+		"audio_codec":    info.audio_codec,
+		"audio_channels": channels,
+		"duration":       duration,
+	}
+}
+
+// video_resolution names the resolution the way release names do. width is checked too
+// since cropped (letterboxed) videos have less height than their resolution class
+func video_resolution(width int, height int) string {
+	switch {
+	case width == 0 && height == 0:
+		return ""
+	case width >= 7600 || height >= 4300:
+		return "4320p"
+	case width >= 3800 || height >= 2100:
+		return "2160p"
+	case width >= 1900 || height >= 1000:
+		return "1080p"
+	case width >= 1260 || height >= 700:
+		return "720p"
+	case height >= 560:
+		return "576p"
+	case height >= 470:
+		return "480p"
+	default:
+		return fmt.Sprintf("%dp", height)
+	}
+}
+
+func channel_layout(channels int) string {
+	switch channels {
+	case 0:
+		return ""
+	case 1:
+		return "1.0"
+	case 2:
+		return "2.0"
+	case 3:
+		return "2.1"
+	case 6:
+		return "5.1"
+	case 7:
+		return "6.1"
+	case 8:
+		return "7.1"
+	default:
+		return fmt.Sprintf("%d.0", channels)
+	}
+}
+
+// -------------------- matroska --------------------
+
+// matroska element ids (with their length marker bits)
+const (
+	mkv_ebml                  = 0x1A45DFA3
+	mkv_segment               = 0x18538067
+	mkv_seek_head             = 0x114D9B74
+	mkv_seek                  = 0x4DBB
+	mkv_seek_id               = 0x53AB
+	mkv_seek_position         = 0x53AC
+	mkv_info                  = 0x1549A966
+	mkv_timecode_scale        = 0x2AD7B1
+	mkv_duration              = 0x4489
+	mkv_tracks                = 0x1654AE6B
+	mkv_track_entry           = 0xAE
+	mkv_track_type            = 0x83
+	mkv_codec_id              = 0x86
+	mkv_video                 = 0xE0
+	mkv_pixel_width           = 0xB0
+	mkv_pixel_height          = 0xBA
+	mkv_colour                = 0x55B0
+	mkv_transfer              = 0x55BA
+	mkv_audio                 = 0xE1
+	mkv_channels              = 0x9F
+	mkv_block_addition_map    = 0x41E4
+	mkv_block_add_id_type     = 0x41E7
+	mkv_cluster               = 0x1F43B675
+	mkv_unknown_size          = -1
+	mkv_track_type_video      = 1
+	mkv_track_type_audio      = 2
+	transfer_pq               = 16
+	transfer_hlg              = 18
+	dolby_vision_config       = 0x64766343 // 'dvcC'
+	dolby_vision_config_large = 0x64767643 // 'dvvC'
+)
+
+var mkv_video_codecs = map[string]string{
+	"V_MPEG4/ISO/AVC":  "AVC",
+	"V_MPEGH/ISO/HEVC": "HEVC",
+	"V_AV1":            "AV1",
+	"V_VP9":            "VP9",
+	"V_VP8":            "VP8",
+	"V_MPEG2":          "MPEG-2",
+	"V_MPEG4/ISO/ASP":  "MPEG-4",
+	"V_MS/VFW/FOURCC":  "VFW",
+}
+
+var mkv_audio_codecs = map[string]string{
+	"A_AAC":          "AAC",
+	"A_AC3":          "AC3",
+	"A_EAC3":         "EAC3",
+	"A_DTS":          "DTS",
+	"A_DTS/EXPRESS":  "DTS",
+	"A_DTS/LOSSLESS": "DTS-HD MA",
+	"A_TRUEHD":       "TrueHD",
+	"A_FLAC":         "FLAC",
+	"A_OPUS":         "Opus",
+	"A_VORBIS":       "Vorbis",
+	"A_MPEG/L3":      "MP3",
+	"A_MPEG/L2":      "MP2",
+	"A_PCM/INT/LIT":  "PCM",
+	"A_PCM/INT/BIG":  "PCM",
+}
+
+type ebml_element struct {
+	id         uint32
+	size       int64
+	data_start int64
+}
+
+func probe_matroska(r io.ReadSeeker, info *StreamInfo) error {
+	header, err := read_ebml_element(r)
+	if err != nil {
+		return err
+	}
+	if header.id != mkv_ebml {
+		return err_unsupported_container
+	}
+	if err := skip_ebml_element(r, header); err != nil {
+		return err
+	}
+
+	segment, err := read_ebml_element(r)
+	if err != nil {
+		return err
+	}
+	if segment.id != mkv_segment {
+		return fmt.Errorf("expected matroska segment, found element 0x%X", segment.id)
+	}
+
+	var timecode_scale uint64 = 1000000
+	var duration float64
+	found_info, found_tracks := false, false
+	seek_positions := make(map[uint32]int64)
+
+	parse_top_level := func(el ebml_element) error {
+		switch el.id {
+		case mkv_info:
+			found_info = true
+			return walk_ebml_children(r, el, func(child ebml_element) error {
+				switch child.id {
+				case mkv_timecode_scale:
+					scale, err := read_ebml_uint(r, child)
+					if err == nil && scale > 0 {
+						timecode_scale = scale
+					}
+					return err
+				case mkv_duration:
+					d, err := read_ebml_float(r, child)
+					duration = d
+					return err
+				}
+				return skip_ebml_element(r, child)
+			})
+		case mkv_tracks:
+			found_tracks = true
+			return walk_ebml_children(r, el, func(child ebml_element) error {
+				if child.id == mkv_track_entry {
+					return read_mkv_track(r, child, info)
+				}
+				return skip_ebml_element(r, child)
+			})
+		case mkv_seek_head:
+			return walk_ebml_children(r, el, func(seek ebml_element) error {
+				if seek.id != mkv_seek {
+					return skip_ebml_element(r, seek)
+				}
+				var id uint32
+				var pos int64 = -1
+				err := walk_ebml_children(r, seek, func(child ebml_element) error {
+					switch child.id {
+					case mkv_seek_id:
+						buf, err := read_ebml_bytes(r, child)
+						if err != nil {
+							return err
+						}
+						for _, b := range buf {
+							id = id<<8 | uint32(b)
+						}
+						return nil
+					case mkv_seek_position:
+						p, err := read_ebml_uint(r, child)
+						pos = int64(p)
+						return err
+					}
+					return skip_ebml_element(r, child)
+				})
+				if err == nil && pos >= 0 {
+					seek_positions[id] = pos
+				}
+				return err
+			})
+		}
+		return skip_ebml_element(r, el)
+	}
+
+	// headers are usually before the first cluster. anything after that is found through the seek head
+	for !(found_info && found_tracks) {
+		el, err := read_ebml_element(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if el.id == mkv_cluster || el.size == mkv_unknown_size {
+			break
+		}
+		if err := parse_top_level(el); err != nil {
+			return err
+		}
+	}
+	for _, id := range []uint32{mkv_info, mkv_tracks} {
+		pos, ok := seek_positions[id]
+		if !ok || (id == mkv_info && found_info) || (id == mkv_tracks && found_tracks) {
+			continue
+		}
+		if _, err := r.Seek(segment.data_start+pos, io.SeekStart); err != nil {
+			return err
+		}
+		el, err := read_ebml_element(r)
+		if err != nil {
+			return err
+		}
+		if el.id == id {
+			if err := parse_top_level(el); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !found_tracks {
+		return fmt.Errorf("no matroska tracks found")
+	}
+	if duration > 0 {
+		info.duration = time.Duration(duration * float64(timecode_scale))
+	}
+	return nil
+}
+
+func read_mkv_track(r io.ReadSeeker, entry ebml_element, info *StreamInfo) error {
+	var track_type uint64
+	var codec_id string
+	var width, height, channels, transfer uint64
+	dolby_vision := false
+
+	err := walk_ebml_children(r, entry, func(el ebml_element) error {
+		switch el.id {
+		case mkv_track_type:
+			t, err := read_ebml_uint(r, el)
+			track_type = t
+			return err
+		case mkv_codec_id:
+			buf, err := read_ebml_bytes(r, el)
+			codec_id = strings.TrimRight(string(buf), "\x00")
+			return err
+		case mkv_video:
+			return walk_ebml_children(r, el, func(video ebml_element) error {
+				var err error
+				switch video.id {
+				case mkv_pixel_width:
+					width, err = read_ebml_uint(r, video)
+				case mkv_pixel_height:
+					height, err = read_ebml_uint(r, video)
+				case mkv_colour:
+					err = walk_ebml_children(r, video, func(colour ebml_element) error {
+						if colour.id == mkv_transfer {
+							t, err := read_ebml_uint(r, colour)
+							transfer = t
+							return err
+						}
+						return skip_ebml_element(r, colour)
+					})
+				default:
+					err = skip_ebml_element(r, video)
+				}
+				return err
+			})
+		case mkv_audio:
+			return walk_ebml_children(r, el, func(audio ebml_element) error {
+				if audio.id == mkv_channels {
+					c, err := read_ebml_uint(r, audio)
+					channels = c
+					return err
+				}
+				return skip_ebml_element(r, audio)
+			})
+		case mkv_block_addition_map:
+			return walk_ebml_children(r, el, func(mapping ebml_element) error {
+				if mapping.id == mkv_block_add_id_type {
+					t, err := read_ebml_uint(r, mapping)
+					if t == dolby_vision_config || t == dolby_vision_config_large {
+						dolby_vision = true
+					}
+					return err
+				}
+				return skip_ebml_element(r, mapping)
+			})
+		}
+		return skip_ebml_element(r, el)
+	})
+	if err != nil {
+		return err
+	}
+
+	if track_type == mkv_track_type_video && info.video_codec == "" {
+		info.video_codec = mkv_video_codecs[codec_id]
+		if info.video_codec == "" {
+			info.video_codec = strings.TrimPrefix(codec_id, "V_")
+		}
+		info.width, info.height = int(width), int(height)
+		info.hdr = hdr_format(int(transfer), dolby_vision)
+
+	} else if track_type == mkv_track_type_audio && info.audio_codec == "" {
+		info.audio_codec = mkv_audio_codecs[codec_id]
+		if info.audio_codec == "" && strings.HasPrefix(codec_id, "A_AAC") {
+			info.audio_codec = "AAC"
+		} else if info.audio_codec == "" {
+			info.audio_codec = strings.TrimPrefix(codec_id, "A_")
+		}
+		info.audio_channels = int(channels)
+	}
+	return nil
+}
+
+// read_ebml_element reads an element id and size. the reader is left at the start of the element's data
+func read_ebml_element(r io.ReadSeeker) (ebml_element, error) {
+	id, _, err := read_vint(r, true)
+	if err != nil {
+		return ebml_element{}, err
+	}
+	size, length, err := read_vint(r, false)
+	if err != nil {
+		return ebml_element{}, err
+	}
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return ebml_element{}, err
+	}
+	el := ebml_element{id: uint32(id), size: int64(size), data_start: start}
+	// all 1s in the size means unknown size (live streams, still being muxed)
+	if size == (1<<(7*length))-1 {
+		el.size = mkv_unknown_size
+	}
+	return el, nil
+}
+
+// read_vint reads a variable length integer. ids keep their length marker bit, sizes don't
+func read_vint(r io.Reader, keep_marker bool) (uint64, int, error) {
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(r, first); err != nil {
+		return 0, 0, err
+	}
+	length := 1
+	for mask := byte(0x80); length <= 8 && first[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, 0, fmt.Errorf("invalid ebml variable length integer")
+	}
+
+	value := uint64(first[0])
+	if !keep_marker {
+		value &= uint64(0xFF >> length)
+	}
+	rest := make([]byte, length-1)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return 0, 0, err
+	}
+	for _, b := range rest {
+		value = value<<8 | uint64(b)
+	}
+	return value, length, nil
+}
+
+func walk_ebml_children(r io.ReadSeeker, parent ebml_element, fn func(ebml_element) error) error {
+	if parent.size == mkv_unknown_size {
+		return fmt.Errorf("element 0x%X has unknown size", parent.id)
+	}
+	end := parent.data_start + parent.size
+	for {
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if pos >= end {
+			break
+		}
+		child, err := read_ebml_element(r)
+		if err != nil {
+			return err
+		}
+		if err := fn(child); err != nil {
+			return err
+		}
+		// children may only be partially read so always continue right after them
+		if child.size != mkv_unknown_size {
+			if _, err := r.Seek(child.data_start+child.size, io.SeekStart); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := r.Seek(end, io.SeekStart)
+	return err
+}
+
+func skip_ebml_element(r io.ReadSeeker, el ebml_element) error {
+	if el.size == mkv_unknown_size {
+		return nil
+	}
+	_, err := r.Seek(el.data_start+el.size, io.SeekStart)
+	return err
+}
+
+func read_ebml_bytes(r io.Reader, el ebml_element) ([]byte, error) {
+	if el.size < 0 || el.size > 1<<20 {
+		return nil, fmt.Errorf("element 0x%X is too large to read", el.id)
+	}
+	buf := make([]byte, el.size)
+	_, err := io.ReadFull(r, buf)
+	return buf, err
+}
+
+func read_ebml_uint(r io.Reader, el ebml_element) (uint64, error) {
+	if el.size > 8 {
+		return 0, fmt.Errorf("element 0x%X is too large for an unsigned int", el.id)
+	}
+	buf, err := read_ebml_bytes(r, el)
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range buf {
+		value = value<<8 | uint64(b)
+	}
+	return value, nil
+}
+
+func read_ebml_float(r io.Reader, el ebml_element) (float64, error) {
+	buf, err := read_ebml_bytes(r, el)
+	if err != nil {
+		return 0, err
+	}
+	switch len(buf) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(buf))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), nil
+	default:
+		return 0, fmt.Errorf("invalid float size %d", len(buf))
+	}
+}
+
+// -------------------- mp4 --------------------
+
+var mp4_video_codecs = map[string]string{
+	"avc1": "AVC",
+	"avc3": "AVC",
+	"hvc1": "HEVC",
+	"hev1": "HEVC",
+	"dvh1": "HEVC",
+	"dvhe": "HEVC",
+	"av01": "AV1",
+	"vp09": "VP9",
+	"vp08": "VP8",
+	"mp4v": "MPEG-4",
+}
+
+var mp4_audio_codecs = map[string]string{
+	"mp4a": "AAC",
+	"ac-3": "AC3",
+	"ec-3": "EAC3",
+	"ac-4": "AC4",
+	"Opus": "Opus",
+	"fLaC": "FLAC",
+	"alac": "ALAC",
+	".mp3": "MP3",
+	"dtsc": "DTS",
+	"dtsh": "DTS-HD",
+	"dtsl": "DTS-HD MA",
+	"lpcm": "PCM",
+}
+
+type mp4_box struct {
+	kind       string
+	size       int64
+	data_start int64
+}
+
+func probe_mp4(r io.ReadSeeker, info *StreamInfo) error {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	found_moov := false
+	err = walk_mp4_boxes(r, 0, end, func(box mp4_box) error {
+		if box.kind != "moov" {
+			return nil
+		}
+		found_moov = true
+		return walk_mp4_boxes(r, box.data_start, box.data_start+box.size, func(child mp4_box) error {
+			switch child.kind {
+			case "mvhd":
+				return read_mp4_mvhd(r, child, info)
+			case "trak":
+				return read_mp4_trak(r, child, info)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if !found_moov {
+		return fmt.Errorf("no mp4 moov box found")
+	}
+	return nil
+}
+
+// walk_mp4_boxes calls fn for every box between start and end. fn does not need to skip the box
+func walk_mp4_boxes(r io.ReadSeeker, start int64, end int64, fn func(mp4_box) error) error {
+	pos := start
+	header := make([]byte, 8)
+	for pos+8 <= end {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+		box := mp4_box{kind: string(header[4:8]), size: int64(binary.BigEndian.Uint32(header[:4])), data_start: pos + 8}
+		switch box.size {
+		case 0:
+			// box extends to the end of the file
+			box.size = end - pos
+		case 1:
+			large := make([]byte, 8)
+			if _, err := io.ReadFull(r, large); err != nil {
+				return err
+			}
+			box.size = int64(binary.BigEndian.Uint64(large))
+			box.data_start += 8
+		}
+		if box.size < box.data_start-pos || pos+box.size > end {
+			return fmt.Errorf("invalid mp4 box size for '%s'", box.kind)
+		}
+		total := box.size
+		box.size -= box.data_start - pos
+
+		if err := fn(box); err != nil {
+			return err
+		}
+		pos += total
+	}
+	return nil
+}
+
+func read_mp4_mvhd(r io.ReadSeeker, box mp4_box, info *StreamInfo) error {
+	buf, err := read_mp4_box(r, box, 32)
+	if err != nil {
+		return err
+	}
+	var timescale, duration uint64
+	if buf[0] == 1 {
+		timescale = uint64(binary.BigEndian.Uint32(buf[20:24]))
+		duration = binary.BigEndian.Uint64(buf[24:32])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(buf[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(buf[16:20]))
+	}
+	if timescale > 0 {
+		info.duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	}
+	return nil
+}
+
+func read_mp4_trak(r io.ReadSeeker, trak mp4_box, info *StreamInfo) error {
+	handler := ""
+	var stsd mp4_box
+	// trak > mdia > (hdlr, minf > stbl > stsd)
+	err := walk_mp4_boxes(r, trak.data_start, trak.data_start+trak.size, func(mdia mp4_box) error {
+		if mdia.kind != "mdia" {
+			return nil
+		}
+		return walk_mp4_boxes(r, mdia.data_start, mdia.data_start+mdia.size, func(child mp4_box) error {
+			switch child.kind {
+			case "hdlr":
+				buf, err := read_mp4_box(r, child, 12)
+				if err != nil {
+					return err
+				}
+				handler = string(buf[8:12])
+			case "minf":
+				return walk_mp4_boxes(r, child.data_start, child.data_start+child.size, func(stbl mp4_box) error {
+					if stbl.kind != "stbl" {
+						return nil
+					}
+					return walk_mp4_boxes(r, stbl.data_start, stbl.data_start+stbl.size, func(box mp4_box) error {
+						if box.kind == "stsd" {
+							stsd = box
+						}
+						return nil
+					})
+				})
+			}
+			return nil
+		})
+	})
+	if err != nil || stsd.kind == "" {
+		return err
+	}
+
+	// stsd is a full box (version, flags) followed by an entry count then the sample entries
+	return walk_mp4_boxes(r, stsd.data_start+8, stsd.data_start+stsd.size, func(entry mp4_box) error {
+		switch handler {
+		case "vide":
+			if info.video_codec != "" {
+				return nil
+			}
+			return read_mp4_visual_entry(r, entry, info)
+		case "soun":
+			if info.audio_codec != "" {
+				return nil
+			}
+			buf, err := read_mp4_box(r, entry, 18)
+			if err != nil {
+				return err
+			}
+			info.audio_codec = mp4_audio_codecs[entry.kind]
+			if info.audio_codec == "" {
+				info.audio_codec = strings.TrimSpace(entry.kind)
+			}
+			info.audio_channels = int(binary.BigEndian.Uint16(buf[16:18]))
+		}
+		return nil
+	})
+}
+
+func read_mp4_visual_entry(r io.ReadSeeker, entry mp4_box, info *StreamInfo) error {
+	// visual sample entries have a fixed 78 byte header before their child boxes
+	const visual_header = 78
+	buf, err := read_mp4_box(r, entry, visual_header)
+	if err != nil {
+		return err
+	}
+	info.video_codec = mp4_video_codecs[entry.kind]
+	if info.video_codec == "" {
+		info.video_codec = strings.TrimSpace(entry.kind)
+	}
+	info.width = int(binary.BigEndian.Uint16(buf[24:26]))
+	info.height = int(binary.BigEndian.Uint16(buf[26:28]))
+
+	transfer := 0
+	dolby_vision := entry.kind == "dvh1" || entry.kind == "dvhe"
+	err = walk_mp4_boxes(r, entry.data_start+visual_header, entry.data_start+entry.size, func(child mp4_box) error {
+		switch child.kind {
+		case "colr":
+			colr, err := read_mp4_box(r, child, 10)
+			if err != nil {
+				return err
+			}
+			if string(colr[:4]) == "nclx" {
+				transfer = int(binary.BigEndian.Uint16(colr[6:8]))
+			}
+		case "dvcC", "dvvC", "dvwC":
+			dolby_vision = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	info.hdr = hdr_format(transfer, dolby_vision)
+	return nil
+}
+
+// read_mp4_box reads the first n bytes of a box's data
+func read_mp4_box(r io.ReadSeeker, box mp4_box, n int64) ([]byte, error) {
+	if box.size < n {
+		return nil, fmt.Errorf("mp4 box '%s' is too small (%d < %d bytes)", box.kind, box.size, n)
+	}
+	if _, err := r.Seek(box.data_start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(r, buf)
+	return buf, err
+}
+
+// hdr_format names the hdr format from the transfer characteristics (ITU-T H.273)
+func hdr_format(transfer int, dolby_vision bool) string {
+	switch {
+	case dolby_vision:
+		return "DV"
+	case transfer == transfer_pq:
+		return "HDR10"
+	case transfer == transfer_hlg:
+		return "HLG"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// info read from a scene/p2p style release name like
+//
+//	Show.Name.2019.S01E02-E03.1080p.WEB-DL.DDP5.1.x264-GROUP
+//	[Group] Show Name - 05 [1080p].mkv
+//
+// fields that were not found are left as zero values, except season and year which are -1
+type ReleaseInfo struct {
+	title         string
+	year          int
+	season        int
+	episodes      []int
+	episode_title string
+	resolution    string
+	source        string
+	codec         string
+	audio         string
+	group         string
+	proper        bool
+	repack        bool
+	// movie edition like "Director's Cut", and the part number of a movie split into several files
+	edition string
+	part    int
+	// where the edition is in the name (start and end of the edition itself), nil if there is none
+	edition_loc []int
+}
+
+// tags are matched against the release name with separators ('.', '_', ' ') intact
+// so each pattern must do its own boundary checks
+var (
+	release_sep                 = `(?:^|[\s._\[\](){}-])`
+	release_end                 = `(?:$|[\s._\[\](){}-])`
+	release_season_ep_pattern   = regexp.MustCompile(`(?i)` + release_sep + `s(\d{1,2})[\s._-]*e(\d{1,4})((?:[\s._]*-[\s._]*e?\d{1,4}|e\d{1,4})*)` + release_end)
+	release_cross_ep_pattern    = regexp.MustCompile(`(?i)` + release_sep + `(\d{1,2})x(\d{2,4})((?:-\d{2,4})*)` + release_end)
+	release_season_pattern      = regexp.MustCompile(`(?i)` + release_sep + `(?:s(\d{1,2})|season[\s._-]*(\d{1,2}))` + release_end)
+	release_absolute_ep_pattern = regexp.MustCompile(`(?i)\s-\s(\d{1,4})(?:v\d)?(?:-(\d{1,4})(?:v\d)?)?` + release_end)
+	release_word_ep_pattern     = regexp.MustCompile(`(?i)` + release_sep + `ep(?:isode)?[\s._-]*(\d{1,4})` + release_end)
+	release_year_pattern        = regexp.MustCompile(`\d+`)
+	release_resolution_pattern  = regexp.MustCompile(`(?i)` + release_sep + `(?:(480|576|720|1080|2160|4320)[pi]|(4k|uhd))` + release_end)
+	release_source_pattern      = regexp.MustCompile(`(?i)` + release_sep + `(web[\s._-]?dl|web[\s._-]?rip|web|blu[\s._-]?ray|bd[\s._-]?rip|br[\s._-]?rip|bdremux|remux|hdtv|dvd[\s._-]?rip|dvd)` + release_end)
+	release_codec_pattern       = regexp.MustCompile(`(?i)` + release_sep + `([xh][\s._-]?26[45]|hevc|avc|av1|vp9|xvid|divx)` + release_end)
+	release_audio_pattern       = regexp.MustCompile(`(?i)` + release_sep + `(dts[\s._-]?hd[\s._-]?ma|dts[\s._-]?x|dts|truehd|atmos|dd\+|ddp|eac3|e-ac-3|dd|ac3|aac|flac|opus|mp3)[\s._-]?(\d\.\d)?` + release_end)
+	release_flags_pattern       = regexp.MustCompile(`(?i)` + release_sep + `(proper|repack)` + release_end)
+	release_edition_pattern     = regexp.MustCompile(`(?i)` + release_sep + `(director'?s[\s._-]?cut|extended(?:[\s._-]?(?:cut|edition))?|unrated|uncut|theatrical(?:[\s._-]?cut)?|remastered|imax|special[\s._-]?edition|ultimate[\s._-]?edition|final[\s._-]?cut|criterion)` + release_end)
+	release_part_pattern        = regexp.MustCompile(`(?i)` + release_sep + `(?:cd|dis[ck]|pt|part)[\s._-]?(\d{1,2})` + release_end)
+	release_leading_group       = regexp.MustCompile(`^\[([^\]]+)\]\s*`)
+	release_trailing_group      = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\[[^\]]*\])?$`)
+)
+
+var release_sources = map[string]string{
+	"webdl":   "WEB-DL",
+	"webrip":  "WEBRip",
+	"web":     "WEB",
+	"bluray":  "BluRay",
+	"bdrip":   "BDRip",
+	"brrip":   "BRRip",
+	"bdremux": "Remux",
+	"remux":   "Remux",
+	"hdtv":    "HDTV",
+	"dvdrip":  "DVDRip",
+	"dvd":     "DVD",
+}
+
+var release_codecs = map[string]string{
+	"x264": "AVC",
+	"h264": "AVC",
+	"avc":  "AVC",
+	"x265": "HEVC",
+	"h265": "HEVC",
+	"hevc": "HEVC",
+	"av1":  "AV1",
+	"vp9":  "VP9",
+	"xvid": "XviD",
+	"divx": "DivX",
+}
+
+var release_audio_codecs = map[string]string{
+	"dtshdma": "DTS-HD MA",
+	"dtsx":    "DTS:X",
+	"dts":     "DTS",
+	"truehd":  "TrueHD",
+	"atmos":   "Atmos",
+	"dd+":     "EAC3",
+	"ddp":     "EAC3",
+	"eac3":    "EAC3",
+	"e-ac-3":  "EAC3",
+	"dd":      "AC3",
+	"ac3":     "AC3",
+	"aac":     "AAC",
+	"flac":    "FLAC",
+	"opus":    "Opus",
+	"mp3":     "MP3",
+}
+
+// editions by their tag without separators
+var release_editions = map[string]string{
+	"directorscut":    "Director's Cut",
+	"director'scut":   "Director's Cut",
+	"extended":        "Extended",
+	"extendedcut":     "Extended",
+	"extendededition": "Extended",
+	"unrated":         "Unrated",
+	"uncut":           "Uncut",
+	"theatrical":      "Theatrical",
+	"theatricalcut":   "Theatrical",
+	"remastered":      "Remastered",
+	"imax":            "IMAX",
+	"specialedition":  "Special Edition",
+	"ultimateedition": "Ultimate Edition",
+	"finalcut":        "Final Cut",
+	"criterion":       "Criterion",
+}
+
+// names that look like a trailing -GROUP but are actually part of a tag
+var release_not_groups = map[string]bool{
+	"dl":  true,
+	"rip": true,
+	"ma":  true,
+	"hd":  true,
+	"x":   true,
+}
+
+// tokens exposed to naming schemes from a parsed release name. all of them take no value
+var release_token_names = []string{
+	"release_title",
+	"episode_title",
+	"year",
+	"resolution",
+	"source",
+	"codec",
+	"audio",
+	"group",
+	"release_flags",
+	"edition",
+	"part",
+}
+
+// parse_release_name reads as much info as it can from a file or directory name.
+// the extension is dropped if the name is a media file
+func parse_release_name(name string) ReleaseInfo {
+	if is_media_file(name) {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	info := ReleaseInfo{
+		year:   -1,
+		season: -1,
+	}
+
+	// title ends where the first tag starts. the episode title is between the episode marker and the tag after it
+	title_end := len(name)
+	ep_end := -1
+	tag_starts := []int{}
+	mark := func(loc []int) {
+		if loc != nil && loc[0] < title_end {
+			title_end = loc[0]
+		}
+		if loc != nil {
+			tag_starts = append(tag_starts, loc[0])
+		}
+	}
+	mark_episode := func(loc []int) {
+		mark(loc)
+		ep_end = loc[1]
+	}
+
+	if match := release_leading_group.FindStringSubmatch(name); match != nil {
+		info.group = strings.TrimSpace(match[1])
+	} else if match := release_trailing_group.FindStringSubmatch(name); match != nil && !release_not_groups[strings.ToLower(match[1])] {
+		info.group = match[1]
+	}
+
+	if loc := release_season_ep_pattern.FindStringSubmatchIndex(name); loc != nil {
+		mark_episode(loc)
+		info.season, _ = strconv.Atoi(name[loc[2]:loc[3]])
+		first, _ := strconv.Atoi(name[loc[4]:loc[5]])
+		info.episodes = episode_range(first, name[loc[6]:loc[7]])
+
+	} else if loc := release_cross_ep_pattern.FindStringSubmatchIndex(name); loc != nil {
+		mark_episode(loc)
+		info.season, _ = strconv.Atoi(name[loc[2]:loc[3]])
+		first, _ := strconv.Atoi(name[loc[4]:loc[5]])
+		info.episodes = episode_range(first, name[loc[6]:loc[7]])
+
+	} else {
+		if loc := release_season_pattern.FindStringSubmatchIndex(name); loc != nil {
+			mark(loc)
+			if loc[2] != -1 {
+				info.season, _ = strconv.Atoi(name[loc[2]:loc[3]])
+			} else {
+				info.season, _ = strconv.Atoi(name[loc[4]:loc[5]])
+			}
+		}
+		if loc := release_word_ep_pattern.FindStringSubmatchIndex(name); loc != nil {
+			mark_episode(loc)
+			first, _ := strconv.Atoi(name[loc[2]:loc[3]])
+			info.episodes = []int{first}
+
+		} else if loc := release_absolute_ep_pattern.FindStringSubmatchIndex(name); loc != nil {
+			mark_episode(loc)
+			first, _ := strconv.Atoi(name[loc[2]:loc[3]])
+			info.episodes = []int{first}
+			if loc[4] != -1 {
+				last, _ := strconv.Atoi(name[loc[4]:loc[5]])
+				info.episodes = episode_range(first, fmt.Sprintf("-%d", last))
+			}
+		}
+	}
+
+	// a year at the very start is most likely part of the title (e.g. "1917", "2012")
+	for _, loc := range release_year_pattern.FindAllStringIndex(name, -1) {
+		if !is_release_year(name, loc) || strings.TrimLeft(name[:loc[0]], "([ ._-") == "" || is_part_of_group(name, loc) {
+			continue
+		}
+		mark([]int{loc[0] - 1})
+		info.year, _ = strconv.Atoi(name[loc[0]:loc[1]])
+		break
+	}
+
+	if loc := release_resolution_pattern.FindStringSubmatchIndex(name); loc != nil {
+		mark(loc)
+		if loc[2] != -1 {
+			info.resolution = name[loc[2]:loc[3]] + "p"
+		} else {
+			info.resolution = "2160p"
+		}
+	}
+	if loc := release_source_pattern.FindStringSubmatchIndex(name); loc != nil {
+		mark(loc)
+		info.source = release_sources[normalize_release_tag(name[loc[2]:loc[3]])]
+	}
+	if loc := release_codec_pattern.FindStringSubmatchIndex(name); loc != nil {
+		mark(loc)
+		info.codec = release_codecs[normalize_release_tag(name[loc[2]:loc[3]])]
+	}
+	if loc := release_audio_pattern.FindStringSubmatchIndex(name); loc != nil {
+		mark(loc)
+		tag := strings.ToLower(name[loc[2]:loc[3]])
+		if tag != "dd+" && tag != "e-ac-3" {
+			tag = normalize_release_tag(tag)
+		}
+		info.audio = release_audio_codecs[tag]
+		if loc[4] != -1 {
+			info.audio += " " + name[loc[4]:loc[5]]
+		}
+	}
+	for _, match := range release_flags_pattern.FindAllStringSubmatchIndex(name, -1) {
+		mark(match)
+		switch strings.ToLower(name[match[2]:match[3]]) {
+		case "proper":
+			info.proper = true
+		case "repack":
+			info.repack = true
+		}
+	}
+
+	if loc := find_edition(name, title_end); loc != nil {
+		mark(loc)
+		info.edition = release_editions[normalize_release_tag(name[loc[2]:loc[3]])]
+		info.edition_loc = loc[2:4]
+	}
+	// only movies are split into parts. "Pilot Part 2" after an episode marker is an episode title
+	if loc := release_part_pattern.FindStringSubmatchIndex(name); loc != nil && ep_end == -1 {
+		mark(loc)
+		info.part, _ = strconv.Atoi(name[loc[2]:loc[3]])
+	}
+
+	title := name[:title_end]
+	title = release_leading_group.ReplaceAllString(title, "")
+	info.title = clean_release_text(title)
+
+	if ep_end != -1 {
+		episode_title_end := len(name)
+		if info.group != "" && !strings.HasPrefix(name, "[") {
+			episode_title_end = strings.LastIndex(name, "-"+info.group)
+		}
+		for _, start := range tag_starts {
+			if start >= ep_end-1 && start < episode_title_end {
+				episode_title_end = start
+			}
+		}
+		if episode_title_end > ep_end {
+			info.episode_title = clean_release_text(name[ep_end:episode_title_end])
+		}
+	}
+
+	return info
+}
+
+// find_edition returns the submatch indexes of the edition in a name whose title ends at title_end (the year
+// or the first other tag). an edition only counts after the title, in brackets, or after " - ".
+// edition words in the title are part of it like "The Extended Family (2020)" or "Uncut Gems (2019)"
+func find_edition(name string, title_end int) []int {
+	for _, loc := range release_edition_pattern.FindAllStringSubmatchIndex(name, -1) {
+		bracketed := strings.ContainsAny(name[loc[0]:loc[2]], "([{")
+		after_dash := strings.HasSuffix(strings.TrimRight(name[:loc[2]], " ._"), " -")
+		if loc[2] >= title_end || bracketed || after_dash {
+			return loc
+		}
+	}
+	return nil
+}
+
+// clean_release_text turns separators into spaces and trims what is left of the tags around the text
+func clean_release_text(text string) string {
+	text = strings.NewReplacer(".", " ", "_", " ").Replace(text)
+	return strings.Trim(strings.Join(strings.Fields(text), " "), " -([")
+}
+
+// episode_range expands the tail of an episode marker like "-E03", "E02E03", or "-03"
+// into every episode from first up to the last number in the tail
+func episode_range(first int, tail string) []int {
+	nums := regexp.MustCompile(`\d+`).FindAllString(tail, -1)
+	if len(nums) == 0 {
+		return []int{first}
+	}
+	last, err := strconv.Atoi(nums[len(nums)-1])
+	if err != nil || last <= first {
+		return []int{first}
+	}
+	episodes := make([]int, 0, last-first+1)
+	for ep := first; ep <= last; ep++ {
+		episodes = append(episodes, ep)
+	}
+	return episodes
+}
+
+// release tags are compared without separators and case ("WEB-DL", "web.dl", "WEBDL")
+func normalize_release_tag(tag string) string {
+	return strings.ToLower(strings.NewReplacer(".", "", "_", "", "-", "", " ", "").Replace(tag))
+}
+
+// skip numbers inside a leading [Group] like "[Group2019] Title"
+func is_part_of_group(name string, loc []int) bool {
+	match := release_leading_group.FindStringIndex(name)
+	return match != nil && loc[0] < match[1]
+}
+
+// a year is a standalone 4 digit number from 1900 to 2099 that is not glued to letters
+func is_release_year(name string, loc []int) bool {
+	if loc[1]-loc[0] != 4 || !(strings.HasPrefix(name[loc[0]:], "19") || strings.HasPrefix(name[loc[0]:], "20")) {
+		return false
+	}
+	is_sep := func(c byte) bool {
+		return strings.IndexByte(" ._-()[]{}", c) != -1
+	}
+	return (loc[0] == 0 || is_sep(name[loc[0]-1])) && (loc[1] == len(name) || is_sep(name[loc[1]]))
+}
+
+// tokens returns the value of every release token for use in naming schemes
+func (info ReleaseInfo) tokens() map[string]string {
+	year := ""
+	if info.year > 0 {
+		year = strconv.Itoa(info.year)
+	}
+	part := ""
+	if info.part > 0 {
+		part = strconv.Itoa(info.part)
+	}
+	flags := make([]string, 0, 2)
+	if info.proper {
+		flags = append(flags, "PROPER")
+	}
+	if info.repack {
+		flags = append(flags, "REPACK")
+	}
+	return map[string]string{
+		"release_title": info.title,
+		"episode_title": info.episode_title,
+		"year":          year,
+		"resolution":    info.resolution,
+		"source":        info.source,
+		"codec":         info.codec,
+		"audio":         info.audio,
+		"group":         info.group,
+		"release_flags": strings.Join(flags, " "),
+		"edition":       info.edition,
+		"part":          part,
+	}
+}
+
+// is_multi_episode reports whether the release covers more than one episode (S01E01-E02)
+func (info ReleaseInfo) is_multi_episode() bool {
+	return len(info.episodes) > 1
+}
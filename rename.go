@@ -4,75 +4,147 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
+	"regexp"
 	"sort"
 	"strings"
 )
 
 type Rename interface {
+	plan() ([]RenameOp, error)
 	rename() error
 }
 
 type SeriesInfo struct {
 	path            string
-	series_type     string
+	series_type     SeriesKind
 	seasons         map[int]string
+	specials        []string
 	movies          []string
 	options         AdditionalOptions
+	season_options  map[int]AdditionalOptions
 }
 
 type MovieInfo struct {
 	path        string
-	movie_type  string
+	movie_type  MovieKind
 	movies      map[string]string
 }
 
+// a single planned rename. season is -1 for movies.
+// with link, new is a link to old in the --link library instead. with copy, old is copied to new and kept
+type RenameOp struct {
+	old     string
+	new     string
+	season  int
+	skip    bool
+	link    bool
+	copy    bool
+}
+
+// is_noop reports whether the file already has its new name
+func (op RenameOp) is_noop() bool {
+	return op.old == op.new
+}
+
 func (info *SeriesInfo) rename() error {
+	ops, err := info.plan()
+	if err != nil {
+		return err
+	}
+	return apply_renames(ops)
+}
+
+// resolved_options are the options each season was planned with, in season order
+func (info *SeriesInfo) resolved_options() []AdditionalOptions {
+	season_nums := make([]int, 0, len(info.season_options))
+	for num := range info.season_options {
+		season_nums = append(season_nums, num)
+	}
+	sort.Ints(season_nums)
+	options := make([]AdditionalOptions, 0, len(season_nums))
+	for _, num := range season_nums {
+		options = append(options, info.season_options[num])
+	}
+	return options
+}
+
+// plan computes the new name of every media file in the series without renaming anything.
+// per season options are asked once and remembered so the series can be planned again
+func (info *SeriesInfo) plan() ([]RenameOp, error) {
+	rule, err := info.series_type.rule()
+	if err != nil {
+		return nil, err
+	}
+	if info.season_options == nil {
+		info.season_options = make(map[int]AdditionalOptions)
+	}
+
 	// for padding of season numbers when renaming: min 2 digits
 	max_season_digits := len(strconv.Itoa(len(info.seasons)))
 	if max_season_digits < 2 {
 		max_season_digits = 2
 	}
 
-	// rename episodes
-	for num, season := range info.seasons {
-		is_valid_type := map[string]bool{
-			"single_season_no_movies": true,
-			"single_season_with_movies": true,
-			"named_seasons": true,
-			"multiple_season_no_movies": true,
-			"multiple_season_with_movies": true,
-		}
-		if !is_valid_type[info.series_type]{
-			return fmt.Errorf("unknown series type: %s", info.series_type)
-		}
+	season_nums := make([]int, 0, len(info.seasons))
+	for num := range info.seasons {
+		season_nums = append(season_nums, num)
+	}
+	sort.Ints(season_nums)
 
-		season_path := filepath.Clean(info.path + "/" + season)
-		fmt.Println("path: ", season_path)
+	// rename episodes
+	ops := make([]RenameOp, 0)
+	// episodes of the seasons before the current one, for absolute episode numbers
+	episodes_before := 0
+	for _, num := range season_nums {
+		season_path := filepath.Clean(info.path + "/" + info.seasons[num])
 
+		// season 0 can be made of several specials directories, numbered one after another in specials_order
+		season_dirs := []string{info.seasons[num]}
+		if num == 0 && len(info.specials) > 0 {
+			season_dirs = info.specials
+		}
 		var media_files []string
-		err := filepath.WalkDir(season_path, func(path string, d os.DirEntry, err error) error {
+		file_dirs := make([]string, 0)
+		file_bases := make([]int, 0)
+		for _, dir := range season_dirs {
+			dir_path := filepath.Clean(info.path + "/" + dir)
+			var dir_files []string
+			err := filepath.WalkDir(dir_path, func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && is_wanted_media_file(d.Name()) {
+					dir_files = append(dir_files, path)
+				}
+				return nil
+			})
 			if err != nil {
-				return err
+				return nil, err
 			}
-			if !d.IsDir() && is_media_file(d.Name()) {
-				media_files = append(media_files, path)
+			sort.Sort(FilenameSort(dir_files))
+
+			// with --specials-range each kind of specials starts at its own range
+			base := 0
+			if num == 0 {
+				kind, _ := special_kind(dir)
+				base = specials_base(kind)
 			}
-			return nil
-		})
-		if err != nil {
-			return err
+			for range dir_files {
+				file_dirs = append(file_dirs, dir_path)
+				file_bases = append(file_bases, base)
+			}
+			media_files = append(media_files, dir_files...)
 		}
-		sort.Sort(FilenameSort(media_files))
 
-		max_ep_digits := len(strconv.Itoa(len(media_files)))
-		if max_ep_digits < 2 {
-			max_ep_digits = 2
-		}
+		max_ep_digits := 2
 		
 		// if additional options are none aka user inputted var, ask for user input
-		season_options := prompt_additional_options(info.options, season_path, 2)
+		season_options, ok := info.season_options[num]
+		if !ok {
+			season_options = prompt_additional_options(info.options, season_path, 2)
+			info.season_options[num] = season_options
+		}
 
 		var ep_num, sen int
 		if season_options.starting_ep_num.is_some() {
@@ -94,62 +166,94 @@ func (info *SeriesInfo) rename() error {
 			ken = false
 		}
 
+		// an episode numbered past --specials-range would take a number from the range of the next kind of specials
+		overflows := func(i int, nth int) error {
+			if specials_range <= 0 || num != 0 || nth <= specials_range {
+				return nil
+			}
+			kind, _ := special_kind(filepath.Base(file_dirs[i]))
+			return fmt.Errorf("more than %d %s episodes in season 0 of %s. use a larger --specials-range", specials_range, kind, info.path)
+		}
+
 		if ken {
-			for _, file := range media_files {
+			for i, file := range media_files {
 				ep_num, err = read_episode_num(file)
 				if err != nil {
-					return err
+					return nil, err
 				}
-				
-				temp_max := len(strconv.Itoa(ep_num))
-				if temp_max > max_ep_digits {
-					max_ep_digits = temp_max
+				if err := overflows(i, ep_num); err != nil {
+					return nil, err
 				}
-
-				ep_nums = append(ep_nums, ep_num)
+				ep_nums = append(ep_nums, file_bases[i]+ep_num)
 			}
 		
 		} else {
-			for range media_files {
-				ep_nums = append(ep_nums, ep_num)
-				ep_num++
+			// files of the same range are numbered one after another. without --specials-range every file is in one range
+			next := make(map[int]int)
+			for i := range media_files {
+				base := file_bases[i]
+				if err := overflows(i, next[base]+ep_num); err != nil {
+					return nil, err
+				}
+				ep_nums = append(ep_nums, base+ep_num+next[base])
+				next[base]++
+			}
+		}
+
+		// for padding of episode numbers when renaming: min 2 digits
+		for _, ep := range ep_nums {
+			if len(strconv.Itoa(ep)) > max_ep_digits {
+				max_ep_digits = len(strconv.Itoa(ep))
 			}
 		}
 
+		// last episode number of the season
+		season_last := 0
 		for i, file := range media_files {
-			title := default_title(info.series_type, season_options.naming_scheme, info.path, season_path)
-			new_name, err := generate_new_name(season_options.naming_scheme,// naming_scheme
-											   max_season_digits, num, 		// season_pad, season_num
-										  	   max_ep_digits, ep_nums[i],	// ep_pad, ep_num 
-										  	   title, file)					// title, file path
+			// double check season number from folder structure with the one in the filename
+			release := parse_release_name(filepath.Base(file))
+			if num != 0 && release.season != -1 && release.season != num {
+				warn("'%s' is in season %d but its filename says season %d", filepath.Base(file), num, release.season)
+			}
+
+			title := default_title(rule, season_options.naming_scheme, info.path, file_dirs[i])
+			abs_num := ep_nums[i]
+			if num != 0 {
+				abs_num = episodes_before + ep_nums[i]
+			}
+			new_name, err := render_new_name(season_options.naming_scheme, SchemeContext{
+				season_pad: max_season_digits,
+				season_num: num,
+				ep_pad:     max_ep_digits,
+				ep_num:     ep_nums[i],
+				abs_path:   file,
+				title:      title,
+				abs_num:    abs_num,
+				entry:      info.path,
+			})
 			if err != nil {
-				return err
+				return nil, err
 			}
+			ops = append(ops, RenameOp{old: file, new: new_name, season: num})
 
-			fmt.Println(fmt.Sprintf("%-*s", 20, file), " --> ", fmt.Sprintf("%*s", 20, new_name))
-			fmt.Println("old", file, "\nnew", new_name)
-			_, err = os.Stat(new_name)
-			if err == nil {
-				fmt.Println("renaming", filepath.Base(file), "to", filepath.Base(new_name) + " failed: file already exists")
-				continue
-			} else if os.IsNotExist(err) {
-				err = os.Rename(file, new_name)
-				if err != nil {
-					return err
-				}
-			} else {
-				return err
+			last := ep_nums[i]
+			if release.is_multi_episode() {
+				last += len(release.episodes) - 1
 			}
+			season_last = max(season_last, last)
+		}
+		// specials are not part of the absolute numbering
+		if num != 0 {
+			episodes_before += season_last
 		}
-		fmt.Println()
 	}
 
 	// rename movies if needed
-	if info.series_type == "single_season_with_movies" || info.series_type == "multiple_season_with_movies" {
+	if rule.HasMovies {
 		for _,movie := range info.movies {
 			files, err := os.ReadDir(info.path + "/" + movie)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			media_files := make([]string, 0)
@@ -157,176 +261,216 @@ func (info *SeriesInfo) rename() error {
 				if file.IsDir() {
 					continue
 				}
-				if is_media_file(file.Name()) {
+				if is_wanted_media_file(file.Name()) {
 					media_files = append(media_files, file.Name())
 				}
 			}
 
 			if len(media_files) > 1 {
-				return fmt.Errorf("multiple media files found in %s for a movie direcotry in %s", movie, info.path+"/"+filepath.Base(movie))
+				return nil, fmt.Errorf("multiple media files found in %s for a movie direcotry in %s", movie, info.path+"/"+filepath.Base(movie))
 			} else if len(media_files) == 0 {
-				return fmt.Errorf("no media files found in %s for a movie directory in %s", movie, info.path+"/"+filepath.Base(movie))
+				return nil, fmt.Errorf("no media files found in %s for a movie directory in %s", movie, info.path+"/"+filepath.Base(movie))
 			}
 
-			new_name := fmt.Sprintf("%s %s%s", filepath.Base(info.path), filepath.Base(movie), filepath.Ext(media_files[0]))
-			fmt.Println(fmt.Sprintf("%-*s", 20, media_files[0]), " --> ", fmt.Sprintf("%*s", 20, new_name))
-			fmt.Println("old", info.path+"/"+movie+"/"+media_files[0], "new", info.path+"/"+movie+"/"+new_name)
-			err = os.Rename(info.path+"/"+movie+"/"+media_files[0], info.path+"/"+movie+"/"+new_name)
+			file := filepath.Join(info.path, movie, media_files[0])
+			// the default name is the series title then the movie's, followed by the movie's year, edition, and provider ids
+			title := fmt.Sprintf("%s %s", clean_title(filepath.Base(info.path)), movie_title(filepath.Base(movie)))
+			default_name := movie_media_tags(filepath.Dir(file), file).tagged_name(title, tag_style_for(none[string]()))
+			new_name, err := render_movie_name(movie_scheme_for(info.options.naming_scheme), SchemeContext{
+				abs_path:   file,
+				title:      movie_title(filepath.Base(movie)),
+				collection: clean_title(filepath.Base(info.path)),
+				season_num: -1,
+				movie:      true,
+			}, default_name)
 			if err != nil {
-				return err
+				return nil, err
 			}
+			ops = append(ops, RenameOp{
+				old: 	file,
+				new: 	new_name,
+				season: -1,
+			})
 		}
 	}
-	return nil
+	return ops, nil
 }
 
 func (info *MovieInfo) rename() error {
-	for dir, file := range info.movies {
-		new_name := clean_title(dir) + filepath.Ext(file)
-		old_name := file
-		if info.movie_type == "movie_set" {
+	ops, err := info.plan()
+	if err != nil {
+		return err
+	}
+	return apply_renames(ops)
+}
+
+func (info *MovieInfo) plan() ([]RenameOp, error) {
+	rule, err := info.movie_type.rule()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(info.movies))
+	for dir := range info.movies {
+		dirs = append(dirs, dir)
+	}
+	sort.Sort(FilenameSort(dirs))
+
+	ops := make([]RenameOp, 0, len(dirs))
+	for _, dir := range dirs {
+		old_name := info.movies[dir]
+		collection := ""
+		if rule.HasMovies {
 			old_name = dir + "/" + old_name
-			new_name = dir + "/" + new_name
+			collection = clean_title(filepath.Base(info.path))
 		}
-
-		fmt.Println(fmt.Sprintf("%-*s", 20, old_name), " --> ", fmt.Sprintf("%*s", 20, new_name))
-		fmt.Println("old", info.path+"/"+old_name, "new", info.path+"/"+new_name)
-		err := os.Rename(info.path+"/"+old_name, info.path+"/"+new_name)
+		file := filepath.Clean(info.path + "/" + old_name)
+		// the default name keeps the year, edition, and provider ids that clean_title takes out
+		default_name := movie_media_tags(filepath.Dir(file), file).tagged_name(movie_title(dir), tag_style_for(none[string]()))
+		new_name, err := render_movie_name(movie_scheme_for(none[string]()), SchemeContext{
+			abs_path:   file,
+			title:      movie_title(dir),
+			collection: collection,
+			season_num: -1,
+			movie:      true,
+		}, default_name)
 		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, RenameOp{
+			old: 	file,
+			new: 	new_name,
+			season: -1,
+		})
+	}
+	return ops, nil
+}
+
+// movie_title is the title of a movie's directory without its year and edition, which have their own tokens.
+// the edition is found like parse_release_name finds it, so edition words in the title are kept
+func movie_title(dir string) string {
+	if loc := parse_release_name(dir).edition_loc; loc != nil {
+		dir = empty_brackets.ReplaceAllString(dir[:loc[0]]+" "+dir[loc[1]:], " ")
+	}
+	return strings.Trim(strings.Join(strings.Fields(clean_title(dir)), " "), " -")
+}
+
+// brackets left empty after the edition in them was taken out
+var empty_brackets = regexp.MustCompile(`\(\s*\)|\[\s*\]|\{\s*\}`)
+
+// render_movie_name is the new path of a movie file by a movie naming scheme. default_name is the name
+// the default naming scheme gives it. presets name it "<title> (<year>)" followed by its edition and provider ids
+func render_movie_name(naming_scheme Option[string], ctx SchemeContext, default_name string) (string, error) {
+	ns, _ := naming_scheme.get()
+	dir, ext := filepath.Dir(ctx.abs_path), filepath.Ext(ctx.abs_path)
+	if _, is_preset, err := find_preset(ns); err != nil {
+		return "", err
+	} else if is_preset {
+		return filepath.Join(dir, preset_movie_name(ctx.title, dir, ctx.abs_path, tag_style_for(naming_scheme))+ext), nil
+	}
+	if naming_scheme.is_none() || ns == "default" {
+		return filepath.Join(dir, default_name+ext), nil
+	}
+
+	scheme, err := compile_naming_scheme(ns)
+	if err != nil {
+		return "", err
+	}
+	new_name, err := scheme.render(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, new_name+ext), nil
+}
+
+// apply_renames renames every planned file that was not skipped.
+// files that already have their new name are left alone quietly.
+// files whose new name is already taken are reported and left alone
+func apply_renames(ops []RenameOp) error {
+	for _, op := range ops {
+		if op.skip || op.is_noop() {
+			continue
+		}
+
+		_, err := os.Stat(op.new)
+		if err == nil && is_finished_transfer(op.old, op.new) {
+			// the file was copied in an earlier run, or a transfer to another device was interrupted right before the original was removed
+			if !op.copy {
+				if err := os.Remove(op.old); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		fmt.Println(fmt.Sprintf("%-*s", 20, filepath.Base(op.old)), " --> ", fmt.Sprintf("%*s", 20, filepath.Base(op.new)))
+		fmt.Println("old", op.old, "\nnew", op.new)
+		if err == nil {
+			fmt.Println("renaming", filepath.Base(op.old), "to", filepath.Base(op.new) + " failed: file already exists")
+			continue
+		} else if os.IsNotExist(err) && op.copy {
+			err = copy_file(op.old, op.new)
+			if err != nil {
+				return err
+			}
+		} else if os.IsNotExist(err) {
+			err = move_file(op.old, op.new)
+			if err != nil {
+				return err
+			}
+		} else {
 			return err
 		}
 	}
+	fmt.Println()
 	return nil
 }
 
-func default_title(series_type string, naming_scheme Option[string], path string, season_path string) string {
-	var title string
-	if series_type == "single_season_no_movies" || series_type == "multiple_season_no_movies" || series_type == "multiple_season_with_movies" {
-		title = filepath.Base(path)
-	} else if series_type == "single_season_with_movies" {
-		title = filepath.Base(season_path)
-	} else if series_type == "named_seasons" {
-		title = filepath.Base(path) + " " + filepath.Base(season_path)
-	}
-	return clean_title(title)
+
+func default_title(rule *TypeRule, naming_scheme Option[string], path string, season_path string) string {
+	return clean_title(rule.title(path, season_path))
 }
 
 func generate_new_name(naming_scheme Option[string], season_pad int, season_num int, ep_pad int, ep_num int, title string, abs_path string) (string, error) {
+	return render_new_name(naming_scheme, SchemeContext{
+		season_pad: season_pad,
+		season_num: season_num,
+		ep_pad:     ep_pad,
+		ep_num:     ep_num,
+		abs_path:   abs_path,
+		title:      title,
+		abs_num:    ep_num,
+	})
+}
+
+// render_new_name is generate_new_name with everything a naming scheme can use, like the absolute episode number
+func render_new_name(naming_scheme Option[string], ctx SchemeContext) (string, error) {
 	var new_name string
 	ns, _ := naming_scheme.get()
+	preset, is_preset, err := find_preset(ns)
+	if err != nil {
+		return "", err
+	} else if is_preset {
+		ns = preset.scheme_for(ctx.season_num, ctx.abs_path)
+	}
+
 	if naming_scheme.is_some() && ns != "default" {
-		scheme, err := naming_scheme.get()
+		scheme, err := compile_naming_scheme(ns)
+		if err != nil {
+			return "", err
+		}
+		new_name, err = scheme.render(ctx)
 		if err != nil {
 			return "", err
 		}
-		// replace <season_num>
-		new_name = regexp.MustCompile(`<season_num(\s*:\s*\d+)?>`).ReplaceAllStringFunc(scheme, func(match string) string {
-			// <season_num: \d+>
-			if strings.Contains(match, ":") {
-				pad := regexp.MustCompile(`\d+`).FindString(match)
-				pad_num, _ := strconv.Atoi(pad)
-				return fmt.Sprintf("%0*d", pad_num, season_num)
-			}
-			// <season_num>
-			return fmt.Sprintf("%0*d", season_pad, season_num)
-		})
-		// replace <episode_num>
-		new_name = regexp.MustCompile(`<episode_num(\s*:\s*\d+)?>`).ReplaceAllStringFunc(new_name, func(match string) string {
-			// <episode_num: \d+>
-			if strings.Contains(match, ":") {
-				pad := regexp.MustCompile(`\d+`).FindString(match)
-				pad_num, _ := strconv.Atoi(pad)
-				return fmt.Sprintf("%0*d", pad_num, ep_num)
-			}
-			// <episode_num>
-			return fmt.Sprintf("%0*d", ep_pad, ep_num)
-		})
-		// replace <self>
-		new_name = regexp.MustCompile(`<self\s*:\s*\d+,\d+>`).ReplaceAllStringFunc(new_name, func(match string) string {
-			// if error, return full base name without extension
-			base_name := filepath.Base(abs_path)
-			base_name = strings.ReplaceAll(base_name, filepath.Ext(base_name), "")
-
-			parts := regexp.MustCompile(`\d+`).FindAllString(match, 2)
-			if len(parts) != 2 {
-				return base_name
-			}
-			start, err := strconv.Atoi(parts[0])
-			if err != nil || start >= len(base_name) {
-				return base_name
-			}
-			end, err := strconv.Atoi(parts[1])
-			if err != nil || end+1 >= len(base_name) {
-				return base_name
-			}
-			return base_name[start:end+1]
-		})
-		// replace <parent> tokens with nth parent's name
-		// lol goodluck: https://regex-vis.com/?r=%3C%28parent%28-parent%29*%28%5Cs*%3A%5Cs*%28%28%5Cd%2B%5Cs*%2C%5Cs*%5Cd%2B%29%7C%28%27%5B%5E%27%5D*%27%29%29%29%3F%7Cp%28-%5Cd%2B%29%3F%28%5Cs*%3A%5Cs*%28%28%5Cd%2B%5Cs*%2C%5Cs*%5Cd%2B%29%7C%28%27%5B%5E%27%5D*%27%29%29%29%3F%29%5Cs*%3E&e=0
-		new_name = regexp.MustCompile(`<(parent(-parent)*(\s*:\s*((\d+(\s*,\s*\d+)?)|('[^']*')))?|p(-\d+)?(\s*:\s*((\d+(\s*,\s*\d+)?)|('[^']*')))?)\s*>`).ReplaceAllStringFunc(new_name, func(match string) string {
-			n, err := parent_token_to_int(match)
-			if err != nil {
-				return new_name
-			}
-			parent_name := nth_parent(abs_path, n)
-
-			// <parent>
-			if !strings.Contains(match, ":") {return parent_name}
-
-			// has ':'
-			// <parent: <value>>
-			trimmed_match := strings.Trim(match, "<>")
-			val := strings.TrimSpace(strings.SplitN(trimmed_match, ":", 2)[1])
-			switch val[0] {
-			// <parent: 1,2>
-			case ',':
-				val := strings.SplitN(val, ",", 2)
-				start, err := strconv.Atoi(val[0])
-				if err != nil || start >= len(parent_name) {
-					return parent_name
-				}
-				end, err := strconv.Atoi(val[1])
-				if err != nil || end+1 >= len(parent_name) {
-					return parent_name
-				}
-				return parent_name[start:end+1]
-
-			// <parent: '<regex_pattern>'>
-			case '\'':
-				regex_pattern := strings.Trim(val, "'")
-				_, err := regexp.Compile(regex_pattern)
-				if err != nil {
-					return parent_name
-				}
-				sub_regexes := split_regex_by_pipe(regex_pattern)
-				for _, re := range sub_regexes {
-					sub_match := regexp.MustCompile(re).FindStringSubmatch(parent_name)
-					if len(sub_match) > 1 {
-						// found a substring match
-						return sub_match[1]
-					}
-				}
-				// did not find a substring match
-				return parent_name
-
-			// <parent: 1>
-			default:
-				start, err := strconv.Atoi(val)
-				if err != nil || start+1 >= len(parent_name) {
-					return parent_name
-				}
-				return parent_name[start:start+1]
-			}
-		})
 		// append ext
-		new_name = filepath.Join(filepath.Dir(abs_path), fmt.Sprintf("%s%s", new_name, filepath.Ext(abs_path)))
+		new_name = filepath.Join(filepath.Dir(ctx.abs_path), fmt.Sprintf("%s%s", new_name, filepath.Ext(ctx.abs_path)))
 
 	} else if naming_scheme.is_none() || ns == "default"{
 		new_name = fmt.Sprintf("S%0*dE%0*d %s%s",
-							season_pad, season_num, 
-							ep_pad, ep_num,
-							title, filepath.Ext(abs_path))
-		new_name = filepath.Join(filepath.Dir(abs_path), new_name)
+							ctx.season_pad, ctx.season_num, 
+							ctx.ep_pad, ctx.ep_num,
+							ctx.title, filepath.Ext(ctx.abs_path))
+		new_name = filepath.Join(filepath.Dir(ctx.abs_path), new_name)
 	}
 
 	return new_name, nil
@@ -1,33 +1,25 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-func series_rename_prereqs(path string, s_type string, options AdditionalOptions) (SeriesInfo, error) {
+func series_rename_prereqs(path string, kind SeriesKind, options AdditionalOptions) (SeriesInfo, error) {
 	// get prerequsite info for renaming series
-	is_valid_type := map[string]bool{
-		"single_season_no_movies": true,
-		"single_season_with_movies": true,
-		"named_seasons": true,
-		"multiple_season_no_movies": true,
-		"multiple_season_with_movies": true,
-	}
-	if !is_valid_type[s_type] {
-		return SeriesInfo{}, fmt.Errorf("unknown series type: %s", s_type)
+	rule, err := kind.rule()
+	if err != nil {
+		return SeriesInfo{}, err
 	}
 
 	// if additional options are none aka user inputted var, ask for user input
 	options = prompt_additional_options(options, path, 1)
 	info := SeriesInfo{
 		path: 				path,
-		series_type: 		s_type,
+		series_type: 		kind,
 		seasons: 			make(map[int]string),
 		movies: 			make([]string, 0),
 		options: 			options,
@@ -37,16 +29,18 @@ func series_rename_prereqs(path string, s_type string, options AdditionalOptions
 	if err != nil {
 		return SeriesInfo{}, err
 	}
-	seasons, movies, err := fetch_series_content(path, s_type, s0)
+	seasons, specials, movies, err := fetch_series_content(path, kind, s0)
 	if err != nil {
 		return SeriesInfo{}, err
 	}
-	if s_type == "single_season_no_movies" {
-		seasons[1] = ""
-	} else if s_type == "single_season_with_movies" {
+	// single season entries are either the season themselves or have it in a subdir with the same name
+	if rule.is_single_season() && rule.SameNameSubdir {
 		seasons[1] = filepath.Base(path)
+	} else if rule.is_single_season() {
+		seasons[1] = ""
 	}
 	info.seasons = seasons
+	info.specials = specials
 	info.movies = movies
 
 	return info, nil
@@ -66,6 +60,9 @@ func series_rename_prereqs(path string, s_type string, options AdditionalOptions
 // return:
 // 	- AdditionalOptions: The additional options for the prompt.
 func prompt_additional_options(options AdditionalOptions, path string, level int8) (AdditionalOptions) {
+	prompt_lock.Lock()
+	defer prompt_lock.Unlock()
+
 	default_ken := some[bool](false)
 	default_sen := some[int](1)
 	default_s0 := some[bool](false)
@@ -87,199 +84,193 @@ func prompt_additional_options(options AdditionalOptions, path string, level int
 	// prompt user for additional options
 	if options.keep_ep_nums.is_none() {
 		fmt.Printf("[INPUT]\nkeep episode numbers for '%s'?\ninputs: (y/n/%sdefault/exit)\n", filepath.Base(path), var_opt[0])
+		var input string
 		for {
-			scanner := bufio.NewScanner(os.Stdin)
-			if scanner.Scan() {
-				input := strings.ToLower(strings.TrimSpace(scanner.Text()))
-
-				if input == "y" || input == "yes" {
-					options.keep_ep_nums = some[bool](true)
-					break
-				} else if input == "n" || input == "no" {
-					options.keep_ep_nums = some[bool](false)
-					break
-				} else if input == "var" && level < 2 {
-					break
-				} else if input == "exit" {
-					return options
-				} else if input == "default" {
-					options.keep_ep_nums = default_ken
-					break
-				} else {
-					fmt.Printf("[ERROR]\ninvalid input, please enter 'y', 'n'%s, 'exit', or 'default'\n", var_opt[1])
-				}
+			input = strings.ToLower(strings.TrimSpace(prompter.ask(level, path, "keep_ep_nums")))
+
+			if input == "y" || input == "yes" {
+				options.keep_ep_nums = some[bool](true)
+				break
+			} else if input == "n" || input == "no" {
+				options.keep_ep_nums = some[bool](false)
+				break
+			} else if input == "var" && level < 2 {
+				break
+			} else if input == "exit" {
+				prompter.accept(level, path, "keep_ep_nums", input)
+				return options
+			} else if input == "default" {
+				options.keep_ep_nums = default_ken
+				break
+			} else {
+				fmt.Printf("[ERROR]\ninvalid input, please enter 'y', 'n'%s, 'exit', or 'default'\n", var_opt[1])
 			}
 		}
+		prompter.accept(level, path, "keep_ep_nums", input)
 	}
 	if options.starting_ep_num.is_none() {
 		fmt.Printf("[INPUT]\nstarting episode number for '%s'?\ninputs: (<int>/%sdefault/exit)\n", filepath.Base(path), var_opt[0])
+		var input string
 		for {
-			scanner := bufio.NewScanner(os.Stdin)
-			if scanner.Scan() {
-				input := strings.ToLower(strings.TrimSpace(scanner.Text()))
-
-				int_input, err := strconv.Atoi(input)
-				if err == nil {
-					options.starting_ep_num = some[int](int_input)
-					break
-				}
-				if input == "default" {
-					options.starting_ep_num = default_sen
-					break
-				} else if input == "var" && level < 2 {
-					break
-				} else if input == "exit" {
-					return options
-				} else {
-					fmt.Printf("[ERROR]\ninvalid input, please enter '<int>'%s, 'exit', or 'default'\n", var_opt[1])
-				}
+			input = strings.ToLower(strings.TrimSpace(prompter.ask(level, path, "starting_ep_num")))
+
+			int_input, err := strconv.Atoi(input)
+			if err == nil {
+				options.starting_ep_num = some[int](int_input)
+				break
+			}
+			if input == "default" {
+				options.starting_ep_num = default_sen
+				break
+			} else if input == "var" && level < 2 {
+				break
+			} else if input == "exit" {
+				prompter.accept(level, path, "starting_ep_num", input)
+				return options
+			} else {
+				fmt.Printf("[ERROR]\ninvalid input, please enter '<int>'%s, 'exit', or 'default'\n", var_opt[1])
 			}
 		}
+		prompter.accept(level, path, "starting_ep_num", input)
 	}
 	if options.has_season_0.is_none() {
 		fmt.Printf("[INPUT]\nspecials/extras directory under '%s' as season 0?\ninputs: (y/n/%sdefault/exit)\n", filepath.Base(path), s0_opt[0])
+		var input string
 		for {
-			scanner := bufio.NewScanner(os.Stdin)
-			if scanner.Scan() {
-				input := strings.ToLower(strings.TrimSpace(scanner.Text()))
-
-				if input == "y" || input == "yes" {
-					options.has_season_0 = some[bool](true)
-					break
-				} else if input == "n" || input == "no" {
-					options.has_season_0 = some[bool](false)
-					break
-				} else if input == "var" && level == 0 {
-					break
-				} else if input == "exit" {
-					return options
-				} else if input == "default" {
-					options.has_season_0 = default_s0
-					break
-				} else {
-					fmt.Printf("[ERROR]\ninvalid input, please enter 'y', 'n'%s, 'exit', or 'default'\n", s0_opt[1])
-				}
+			input = strings.ToLower(strings.TrimSpace(prompter.ask(level, path, "has_season_0")))
+
+			if input == "y" || input == "yes" {
+				options.has_season_0 = some[bool](true)
+				break
+			} else if input == "n" || input == "no" {
+				options.has_season_0 = some[bool](false)
+				break
+			} else if input == "var" && level == 0 {
+				break
+			} else if input == "exit" {
+				prompter.accept(level, path, "has_season_0", input)
+				return options
+			} else if input == "default" {
+				options.has_season_0 = default_s0
+				break
+			} else {
+				fmt.Printf("[ERROR]\ninvalid input, please enter 'y', 'n'%s, 'exit', or 'default'\n", s0_opt[1])
 			}
 		}
+		prompter.accept(level, path, "has_season_0", input)
 	}
-	naming_scheme, _ := options.naming_scheme.get()
-	if options.naming_scheme.is_none() || naming_scheme != "default" {
+	if options.naming_scheme.is_none() {
 		fmt.Printf("[INPUT]\nnaming scheme for '%s'?\ninputs: (<naming scheme>/%sdefault)\n", filepath.Base(path), var_opt[0])
+		var input string
 		for {
-			scanner := bufio.NewScanner(os.Stdin)
-			if scanner.Scan() {
-				input := scanner.Text()
-				input = strings.TrimSpace(input)
-
-				if strings.ToLower(input) == "var" && level < 2 {
-					break
-				} else if strings.ToLower(input) == "default" {
-					options.naming_scheme = default_ns
-					break
-				} else if strings.ToLower(input) == "exit" {
-					return options
-				} else if err := validate_naming_scheme(input); err == nil && input != "var" {
-					options.naming_scheme = some[string](input)
-					break
-				} else {
-					fmt.Printf("[ERROR]\ninvalid input, please enter 'y', 'n'%s, 'default', 'exit', or a valid naming scheme\n", var_opt[1])
-					fmt.Println("input:", input)
-					if err != nil { 
-						fmt.Println("naming scheme error:", err)
-					} else { 
-						fmt.Println("error: invalid input") }
-				}
+			input = strings.TrimSpace(prompter.ask(level, path, "naming_scheme"))
+
+			if strings.ToLower(input) == "var" && level < 2 {
+				break
+			} else if strings.ToLower(input) == "default" {
+				options.naming_scheme = default_ns
+				break
+			} else if strings.ToLower(input) == "exit" {
+				prompter.accept(level, path, "naming_scheme", input)
+				return options
+			} else if err := validate_naming_scheme(input); err == nil && input != "var" {
+				options.naming_scheme = some[string](input)
+				break
+			} else {
+				fmt.Printf("[ERROR]\ninvalid input, please enter 'y', 'n'%s, 'default', 'exit', or a valid naming scheme\n", var_opt[1])
+				fmt.Println("input:", input)
+				if err != nil { 
+					fmt.Println("naming scheme error:", err)
+				} else { 
+					fmt.Println("error: invalid input") }
 			}
 		}
+		prompter.accept(level, path, "naming_scheme", input)
 	}
 
 	return options
 }
 
-func fetch_series_content(path string, s_type string, has_season_0 bool) (map[int]string, []string, error) {
+// fetch_series_content reads the seasons and movies of an entry. every specials/extras directory is part of season 0,
+// in specials_order. seasons[0] is the first one
+func fetch_series_content(path string, kind SeriesKind, has_season_0 bool) (map[int]string, []string, []string, error) {
 	seasons := make(map[int]string)
+	specials := make([]string, 0)
 	movies := make([]string, 0)
-	
+
+	rule, err := kind.rule()
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	subdirs, err := os.ReadDir(path)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	extras_pattern := regexp.MustCompile(`^(?i)(specials?|extras?|o(v|n)a)`)
+	extras_pattern := series_extras_pattern
 	for _, subdir := range subdirs {
 		if !subdir.IsDir() {
 			continue
 		}
 
-		// skip subdir with same name as directory if s_type is 'single_season_with_movies'
+		// skip subdir with same name as directory if the type has its season there
 		// season is assigned outside of this function
-		if s_type == "single_season_with_movies" && subdir.Name() == filepath.Base(path) {
+		if rule.SameNameSubdir && subdir.Name() == filepath.Base(path) {
+			trace("'%s': season 1, same name as the entry", subdir.Name())
 			continue
 		}
 
 		if has_season_0 {
-			if seasons[0] != "" {
-				return nil, nil, fmt.Errorf("multiple specials/extras directories found in %s", path)
-			}
-
-			if extras_pattern.MatchString(subdir.Name()) {
-				seasons[0] = subdir.Name()
-				continue
-			}
-		}
-
-		if s_type == "single_season_no_movies" {
-			continue
-		} else if s_type == "single_season_with_movies"{
 			if extras_pattern.MatchString(subdir.Name()) {
-				continue
-			} else {
-				movies = append(movies, subdir.Name())
+				trace("'%s': season 0, matches extras pattern '%s'", subdir.Name(), extras_pattern)
+				specials = append(specials, subdir.Name())
 				continue
 			}
 		}
 
 		// get season number from subdir name
-		var season_name_pattern *regexp.Regexp
-		if s_type == "named_seasons" {
-			season_name_pattern = regexp.MustCompile(`^(\d+)\..*$`)
-		} else if s_type == "multiple_season_no_movies" || s_type == "multiple_season_with_movies" {
-			season_name_pattern = regexp.MustCompile(`^(?i)season\s+(\d+).*$`)
-		} else {
-			return nil, nil, fmt.Errorf("unknown series type: %s; series type must be one of 'named_seasons', 'multiple_season_no_movies', 'multiple_season_with_movies'", s_type)
-		}
-		if season_name_pattern == nil {
-			return nil, nil, fmt.Errorf("unknown series type: %s; series type must be one of 'named_seasons', 'multiple_season_no_movies', 'multiple_season_with_movies'", s_type)
+		num, is_season, how, err := rule.season_of(subdir.Name())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s in %s", err, path)
 		}
-
-		season_num := season_name_pattern.FindStringSubmatch(subdir.Name())
-		if season_num == nil {
-			if s_type == "multiple_season_with_movies" {
-				if extras_pattern.MatchString(subdir.Name()) {
-					continue
+		if !is_season {
+			if !rule.HasMovies {
+				if rule.is_single_season() {
+					trace("'%s': ignored, %s has no season subdirectories", subdir.Name(), kind)
 				} else {
-					movies = append(movies, subdir.Name())
-					continue
+					trace("'%s': ignored, %s", subdir.Name(), how)
 				}
+			} else if extras_pattern.MatchString(subdir.Name()) {
+				trace("'%s': ignored, matches extras pattern '%s' but season 0 is off", subdir.Name(), extras_pattern)
+			} else {
+				trace("'%s': movie, not a season or specials/extras", subdir.Name())
+				movies = append(movies, subdir.Name())
 			}
 			continue
 		}
 
-		// season_num[0] is the whole string so we only need season_num[1] (first matched group)
-		num, err := strconv.Atoi(season_num[1])
-		if err != nil {
-			return nil, nil, err
-		}
+		trace("'%s': season %d, %s", subdir.Name(), num, how)
 		seasons[num] = subdir.Name()
 	}
 
-	return seasons, movies, nil
+	if len(specials) > 0 {
+		sort_specials(specials)
+		seasons[0] = specials[0]
+		if len(specials) > 1 {
+			trace("season 0 is '%s' in that order", strings.Join(specials, "', '"))
+		}
+	}
+	return seasons, specials, movies, nil
 }
 
-func movie_rename_prereqs(path string, m_type string) (MovieInfo, error) {
+func movie_rename_prereqs(path string, kind MovieKind) (MovieInfo, error) {
+	rule, err := kind.rule()
+	if err != nil {
+		return MovieInfo{}, err
+	}
 	info := MovieInfo{
 		path: 			path,
-		movie_type: 	m_type,
+		movie_type: 	kind,
 		movies: 		make(map[string]string),
 	}
 
@@ -288,20 +279,21 @@ func movie_rename_prereqs(path string, m_type string) (MovieInfo, error) {
 		return MovieInfo{}, err
 	}
 
-	extras_pattern := regexp.MustCompile(`^(?i)specials?|extras?|trailers?|ova`)
+	extras_pattern := movie_extras_pattern
 
 	for _, subdir := range subdirs {
-		if m_type == "standalone" {
+		// the entry itself is the movie unless its subdirectories are
+		if !rule.HasMovies {
 			if subdir.IsDir() && extras_pattern.MatchString(subdir.Name()) {
 				continue
 			}
 
-			if is_media_file(subdir.Name()) {
+			if is_wanted_media_file(subdir.Name()) {
 				if len(info.movies) == 0 {
 					info.movies[filepath.Base(path)] = subdir.Name()
 					continue
 				} else {
-					return MovieInfo{}, fmt.Errorf("multiple media files found in %s for an entry marked as a standalone movie", path)
+					return MovieInfo{}, fmt.Errorf("multiple media files found in %s for an entry marked as %s", path, kind)
 				}
 			}
 		}
@@ -310,7 +302,7 @@ func movie_rename_prereqs(path string, m_type string) (MovieInfo, error) {
 			continue
 		}
 
-		if m_type == "movie_set" {
+		if rule.HasMovies {
 			if extras_pattern.MatchString(subdir.Name()) {
 				continue
 			}
@@ -322,7 +314,7 @@ func movie_rename_prereqs(path string, m_type string) (MovieInfo, error) {
 
 			movie_count := 0
 			for _, file := range files {
-				if is_media_file(file.Name()) {
+				if is_wanted_media_file(file.Name()) {
 					if movie_count > 0 {
 						return MovieInfo{}, fmt.Errorf("multiple media files found in %s", path)
 					}
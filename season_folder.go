@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// words that name a season folder when followed by a number, like "Season 1", "Series 2" (UK), or "Staffel 3".
+// more can be added with "season_words" in a rules file
+var builtin_season_words = []string{
+	"season",
+	"series",
+	"staffel",
+	"saison",
+	"temporada",
+	"stagione",
+	"seizoen",
+	"sezon",
+	"kausi",
+}
+
+var season_words = append([]string{}, builtin_season_words...)
+
+// the number after a season word is digits or roman numerals ("Season01", "Season 1 - Title", "Season II").
+// "S01" on its own is a season folder too
+var (
+	season_word_pattern  = compile_season_word_pattern(season_words)
+	season_short_pattern = regexp.MustCompile(`^(?i)s(\d{1,3})(?:$|[\s._\-\[(])`)
+)
+
+func compile_season_word_pattern(words []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	return regexp.MustCompile(`^(?i)(?:` + strings.Join(quoted, "|") + `)[\s._-]*(\d{1,3}|[ivxlc]+)(?:$|[\s._\-\[(])`)
+}
+
+// add_season_words makes words from a rules file name season folders as well
+func add_season_words(words []string, source string) error {
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" || strings.ContainsAny(word, "0123456789") {
+			return fmt.Errorf("invalid season word '%s' in %s. must not be empty or contain digits", word, source)
+		}
+		season_words = append(season_words, strings.ToLower(word))
+	}
+	season_word_pattern = compile_season_word_pattern(season_words)
+	return nil
+}
+
+// season_folder_number reads the season number from a season folder name
+// like "Season 1", "Season01", "S01", "Series 2", "Staffel 3", "Saison 4", "Temporada 5", or "Season II"
+func season_folder_number(name string) (int, bool) {
+	if match := season_word_pattern.FindStringSubmatch(name); match != nil {
+		if num, err := strconv.Atoi(match[1]); err == nil {
+			return num, true
+		}
+		return parse_roman(match[1])
+	}
+	if match := season_short_pattern.FindStringSubmatch(name); match != nil {
+		num, err := strconv.Atoi(match[1])
+		return num, err == nil
+	}
+	return 0, false
+}
+
+func is_season_folder(name string) bool {
+	_, ok := season_folder_number(name)
+	return ok
+}
+
+// parse_roman reads roman numerals up to 99 (XCIX). numerals not written the usual way ("IIII", "IC") are not numbers
+func parse_roman(s string) (int, bool) {
+	values := map[byte]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100}
+	s = strings.ToLower(s)
+	total := 0
+	for i := 0; i < len(s); i++ {
+		value, ok := values[s[i]]
+		if !ok {
+			return 0, false
+		}
+		if i+1 < len(s) && values[s[i+1]] > value {
+			total -= value
+		} else {
+			total += value
+		}
+	}
+	if total < 1 || total > 99 || to_roman(total) != s {
+		return 0, false
+	}
+	return total, true
+}
+
+func to_roman(n int) string {
+	numerals := []struct {
+		value  int
+		symbol string
+	}{
+		{90, "xc"}, {50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+	}
+	var roman strings.Builder
+	for _, numeral := range numerals {
+		for n >= numeral.value {
+			roman.WriteString(numeral.symbol)
+			n -= numeral.value
+		}
+	}
+	return roman.String()
+}
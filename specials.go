@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// kinds of specials directories in the order their episodes are numbered in season 0.
+// kinds left out of --specials-order keep this order after the given ones
+var builtin_specials_order = []string{"specials", "ova", "ona", "extras"}
+
+var specials_order = append([]string{}, builtin_specials_order...)
+
+// if not 0, each kind of specials gets its own range of this many episode numbers in season 0
+// (specials 1-100, ova 101-200, ...) instead of continuing where the previous kind left off
+var specials_range = 0
+
+// values of the <special_kind> token
+var special_kind_names = map[string]string{
+	"specials": "Special",
+	"ova":      "OVA",
+	"ona":      "ONA",
+	"extras":   "Extra",
+}
+
+// special_kind returns the kind of a specials directory ("specials", "ova", "ona", or "extras") from its name
+func special_kind(name string) (string, bool) {
+	match := series_extras_pattern.FindString(name)
+	if match == "" {
+		return "", false
+	}
+	kind := strings.ToLower(match)
+	if !strings.HasSuffix(kind, "s") && kind != "ova" && kind != "ona" {
+		kind += "s"
+	}
+	return kind, true
+}
+
+// special_kind_of_path returns the <special_kind> of a file in a specials directory.
+// the closest directory up from the file that is a specials directory decides it
+func special_kind_of_path(path string) string {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if kind, ok := special_kind(filepath.Base(dir)); ok {
+			return special_kind_names[kind]
+		}
+	}
+	return ""
+}
+
+// specials_rank is the position of a kind in specials_order
+func specials_rank(kind string) int {
+	for i, ordered := range specials_order {
+		if ordered == kind {
+			return i
+		}
+	}
+	return len(specials_order)
+}
+
+// sort_specials orders specials directories by their kind in specials_order then by name
+func sort_specials(dirs []string) {
+	sort.SliceStable(dirs, func(i, j int) bool {
+		kind_i, _ := special_kind(dirs[i])
+		kind_j, _ := special_kind(dirs[j])
+		if specials_rank(kind_i) != specials_rank(kind_j) {
+			return specials_rank(kind_i) < specials_rank(kind_j)
+		}
+		return compare_filenames(dirs[i], dirs[j])
+	})
+}
+
+// specials_base is the episode number right before the range of a kind of specials when specials_range is set
+func specials_base(kind string) int {
+	if specials_range <= 0 {
+		return 0
+	}
+	return specials_rank(kind) * specials_range
+}
+
+// set_specials_order puts the given kinds first in the order specials are numbered
+func set_specials_order(order []string) error {
+	seen := make(map[string]bool)
+	ordered := make([]string, 0, len(builtin_specials_order))
+	for _, kind := range order {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if _, ok := special_kind_names[kind]; !ok {
+			return fmt.Errorf("invalid specials kind '%s'. must be one of %s", kind, strings.Join(builtin_specials_order, ", "))
+		} else if seen[kind] {
+			return fmt.Errorf("specials kind '%s' is given more than once", kind)
+		}
+		seen[kind] = true
+		ordered = append(ordered, kind)
+	}
+	for _, kind := range builtin_specials_order {
+		if !seen[kind] {
+			ordered = append(ordered, kind)
+		}
+	}
+	specials_order = ordered
+	return nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// State remembers a fingerprint of every entry left fully renamed by a previous run (--state).
+// entries whose files have not changed since are not scanned again
+type State struct {
+	path string
+	// the options of the run. an entry renamed with different ones is scanned again
+	options  AdditionalOptions
+	Settings string            `json:"settings"`
+	Entries  map[string]string `json:"entries"`
+	// the options each series entry was renamed with, once per season. options left as var are saved as answered
+	Options map[string][][]string `json:"options,omitempty"`
+}
+
+// the state of the current run. nil when --state is not given
+var state *State
+
+// load_state reads a state file. a missing file is an empty state.
+// if the settings of the run differ from the ones the file was made with, every entry is scanned again
+func load_state(path string, settings string, options AdditionalOptions) (*State, error) {
+	loaded := &State{path: path, options: options, Settings: settings, Entries: make(map[string]string), Options: make(map[string][][]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return loaded, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var saved State
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", path, err)
+	}
+	if saved.Settings == settings && saved.Entries != nil {
+		loaded.Entries = saved.Entries
+		if saved.Options != nil {
+			loaded.Options = saved.Options
+		}
+	}
+	return loaded, nil
+}
+
+// changed_entries drops the entries whose fingerprint is the same as the one saved
+func (s *State) changed_entries(entries []string) ([]string, error) {
+	if s == nil {
+		return entries, nil
+	}
+	unchanged := make([]bool, len(entries))
+	err := run_jobs(len(entries), func(i int) error {
+		saved, ok := s.Entries[entries[i]]
+		if !ok || !s.same_options(entries[i]) {
+			return nil
+		}
+		current, err := fingerprint(entries[i])
+		unchanged[i] = current == saved
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0, len(entries))
+	for i, entry := range entries {
+		if !unchanged[i] {
+			changed = append(changed, entry)
+		}
+	}
+	return changed, nil
+}
+
+// same_options reports whether an entry was renamed with the options of the run.
+// an option left as var in the run matches whatever was answered for the entry
+func (s *State) same_options(entry string) bool {
+	current := option_fields(s.options)
+	for _, saved := range s.Options[entry] {
+		for i, field := range current {
+			if field != "" && (i >= len(saved) || saved[i] != field) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// option_fields are the values of the options as saved in the state file. an option left as var is ""
+func option_fields(options AdditionalOptions) []string {
+	field := func(value any, err error) string {
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+	return []string{
+		field(options.keep_ep_nums.get()),
+		field(options.starting_ep_num.get()),
+		field(options.has_season_0.get()),
+		field(options.naming_scheme.get()),
+	}
+}
+
+// record saves the fingerprint of an entry after its renames were applied, along with the options
+// each of its seasons was renamed with (none for movies).
+// the entry is only remembered if every planned file now has its new name
+func (s *State) record(entry string, ops []RenameOp, options []AdditionalOptions) error {
+	if s == nil {
+		return nil
+	}
+	delete(s.Entries, entry)
+	delete(s.Options, entry)
+	// entries moved out with --dest are gone
+	if _, err := os.Stat(entry); err != nil {
+		return s.save()
+	}
+	if is_renamed(ops) {
+		current, err := fingerprint(entry)
+		if err != nil {
+			return err
+		}
+		s.Entries[entry] = current
+		for _, resolved := range options {
+			s.Options[entry] = append(s.Options[entry], option_fields(resolved))
+		}
+	}
+	return s.save()
+}
+
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// is_renamed reports whether every op was applied and its file is at the new name
+func is_renamed(ops []RenameOp) bool {
+	for _, op := range ops {
+		if op.skip {
+			return false
+		}
+		if op.link {
+			if !is_linked(op.old, op.new) {
+				return false
+			}
+			continue
+		}
+		if op.copy {
+			if _, err := os.Stat(op.new); err != nil {
+				return false
+			}
+			continue
+		}
+		if _, err := os.Stat(op.new); err != nil {
+			return false
+		}
+		if _, err := os.Stat(op.old); err == nil && !op.is_noop() {
+			return false
+		}
+	}
+	return true
+}
+
+// fingerprint hashes the path, size, and modification time of every file in an entry
+func fingerprint(entry string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(entry, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(entry, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, fmt.Sprintf("%s\x00%d\x00%d", filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(files, "\n")))), nil
+}
+
+// state_settings is everything besides the files and the options of each entry that changes what an entry is
+// renamed to, or where. a state file made with other settings is not used
+func state_settings(args Args) (string, error) {
+	option := func(value any, err error) string {
+		if err != nil {
+			return "default"
+		}
+		return fmt.Sprint(value)
+	}
+	// the rules file is hashed so editing it is noticed
+	rules := ""
+	if args.rules != "" {
+		data, err := os.ReadFile(args.rules)
+		if err != nil {
+			return "", err
+		}
+		rules = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+	var filters []string
+	for _, filter := range args.file_filters {
+		filters = append(filters, fmt.Sprintf("%t:%s", filter.exclude, filter.pattern))
+	}
+	var overrides []string
+	for _, override := range args.type_overrides {
+		overrides = append(overrides, override.pattern+"="+override.kind)
+	}
+	return strings.Join([]string{
+		option(args.movie_naming_scheme.get()),
+		option(args.tag_style.get()),
+		rules,
+		strings.Join(specials_order, ","),
+		fmt.Sprint(specials_range),
+		strings.Join(filters, ","),
+		strings.Join(overrides, ","),
+		// entries linked or copied into a library are left in place, so they are not renamed for a run
+		// without the same --link, --dest, and --copy
+		args.link,
+		args.dest,
+		fmt.Sprint(args.copy),
+	}, "|"), nil
+}
@@ -0,0 +1,10 @@
+//go:build darwin || freebsd || netbsd || openbsd
+
+package main
+
+import "syscall"
+
+const (
+	ioctl_get_termios = syscall.TIOCGETA
+	ioctl_set_termios = syscall.TIOCSETA
+)
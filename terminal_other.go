@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package main
+
+import "fmt"
+
+func enable_raw_mode() (func() error, error) {
+	return nil, fmt.Errorf("the terminal ui is not supported on this platform")
+}
+
+func terminal_size() (int, int) {
+	return 80, 24
+}
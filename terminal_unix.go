@@ -0,0 +1,59 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+type winsize struct {
+	rows uint16
+	cols uint16
+	x_px uint16
+	y_px uint16
+}
+
+// enable_raw_mode turns off line buffering and echo on stdin so single key presses can be read.
+// the returned function restores the previous terminal state
+func enable_raw_mode() (func() error, error) {
+	fd := os.Stdin.Fd()
+	var old syscall.Termios
+	if err := ioctl(fd, ioctl_get_termios, unsafe.Pointer(&old)); err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, ioctl_set_termios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return ioctl(fd, ioctl_set_termios, unsafe.Pointer(&old))
+	}, nil
+}
+
+// terminal_size returns the width and height of the terminal, falling back to 80x24
+func terminal_size() (int, int) {
+	var size winsize
+	if err := ioctl(os.Stdout.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil || size.cols == 0 || size.rows == 0 {
+		return 80, 24
+	}
+	return int(size.cols), int(size.rows)
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
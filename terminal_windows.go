@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	enable_processed_input             = 0x0001
+	enable_line_input                  = 0x0002
+	enable_echo_input                  = 0x0004
+	enable_virtual_terminal_input      = 0x0200
+	enable_virtual_terminal_processing = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	proc_set_console_mode          = kernel32.NewProc("SetConsoleMode")
+	proc_get_console_screen_buffer = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+type console_screen_buffer_info struct {
+	size_x, size_y                                       int16
+	cursor_x, cursor_y                                   int16
+	attributes                                           uint16
+	window_left, window_top, window_right, window_bottom int16
+	max_x, max_y                                         int16
+}
+
+// enable_raw_mode turns off line buffering and echo on the console and turns on
+// escape sequences for input and output. the returned function restores the previous console modes
+func enable_raw_mode() (func() error, error) {
+	stdin, stdout := syscall.Stdin, syscall.Stdout
+	var old_in, old_out uint32
+	if err := syscall.GetConsoleMode(stdin, &old_in); err != nil {
+		return nil, fmt.Errorf("stdin is not a console: %w", err)
+	}
+	if err := syscall.GetConsoleMode(stdout, &old_out); err != nil {
+		return nil, fmt.Errorf("stdout is not a console: %w", err)
+	}
+
+	raw_in := old_in&^(enable_processed_input|enable_line_input|enable_echo_input) | enable_virtual_terminal_input
+	if err := set_console_mode(stdin, raw_in); err != nil {
+		return nil, err
+	}
+	if err := set_console_mode(stdout, old_out|enable_virtual_terminal_processing); err != nil {
+		set_console_mode(stdin, old_in)
+		return nil, err
+	}
+
+	return func() error {
+		if err := set_console_mode(stdin, old_in); err != nil {
+			return err
+		}
+		return set_console_mode(stdout, old_out)
+	}, nil
+}
+
+// terminal_size returns the width and height of the console window, falling back to 80x24
+func terminal_size() (int, int) {
+	var info console_screen_buffer_info
+	ok, _, _ := proc_get_console_screen_buffer.Call(uintptr(syscall.Stdout), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 80, 24
+	}
+	return int(info.window_right-info.window_left) + 1, int(info.window_bottom-info.window_top) + 1
+}
+
+func set_console_mode(handle syscall.Handle, mode uint32) error {
+	ok, _, err := proc_set_console_mode.Call(uintptr(handle), uintptr(mode))
+	if ok == 0 {
+		return err
+	}
+	return nil
+}
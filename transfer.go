@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// a file being copied to another device is written next to its destination with this suffix
+// and only renamed to the destination once it is complete and verified. a part file left
+// by an interrupted transfer is resumed from where it stopped
+const transfer_part_suffix = ".gorn-part"
+
+// where transfer progress is written. nil turns progress off
+var progress_output io.Writer = os.Stdout
+
+// how often transfer progress is written
+const progress_interval = time.Second
+
+// move_file moves a file to its new path, creating the directories on the way there.
+// across devices where renaming is not possible, the file is copied, verified, and then removed
+func move_file(old string, new string) error {
+	if filepath.Dir(old) != filepath.Dir(new) {
+		if err := os.MkdirAll(filepath.Dir(new), 0755); err != nil {
+			return err
+		}
+	}
+	err := os.Rename(old, new)
+	if err == nil || !is_cross_device(err) {
+		return err
+	}
+	return transfer_file(old, new)
+}
+
+// transfer_file moves a file to another device by copying it then removing the original
+func transfer_file(old string, new string) error {
+	if err := copy_file(old, new); err != nil {
+		return err
+	}
+	return os.Remove(old)
+}
+
+// copy_file copies a file into the part file (resuming it if there is one), syncs it to disk,
+// checks that its size and hash are the same as the original, then renames it to the destination
+func copy_file(old string, new string) error {
+	if err := os.MkdirAll(filepath.Dir(new), 0755); err != nil {
+		return err
+	}
+	src, err := os.Open(old)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	src_info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	part := new + transfer_part_suffix
+	dst, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE, src_info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	// a part file bigger than the original is not from this file
+	done, err := dst.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if done > src_info.Size() {
+		if err := dst.Truncate(0); err != nil {
+			return err
+		}
+		done = 0
+	}
+	if done > 0 {
+		fmt.Fprintf(progress_writer(), "resuming transfer of %s at %s\n", filepath.Base(old), format_size(done))
+	}
+	if _, err := dst.Seek(done, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := src.Seek(done, io.SeekStart); err != nil {
+		return err
+	}
+
+	progress := &TransferProgress{name: filepath.Base(old), done: done, total: src_info.Size()}
+	if _, err := io.Copy(io.MultiWriter(dst, progress), src); err != nil {
+		return err
+	}
+	progress.finish()
+	if err := dst.Sync(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	same, err := same_contents(old, part)
+	if err != nil {
+		return err
+	}
+	if !same {
+		// start over next time instead of resuming a part file that is wrong
+		os.Remove(part)
+		return fmt.Errorf("transfer of %s to %s failed: copied file does not match the original", old, new)
+	}
+	if err := os.Chtimes(part, src_info.ModTime(), src_info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(part, new)
+}
+
+// is_finished_transfer reports whether new is a complete copy of old in another directory,
+// left by --copy or by a transfer that was interrupted before it could remove old
+func is_finished_transfer(old string, new string) bool {
+	if filepath.Dir(old) == filepath.Dir(new) {
+		return false
+	}
+	same, err := same_contents(old, new)
+	return err == nil && same
+}
+
+// same_contents compares the size and sha256 hash of two files
+func same_contents(a string, b string) (bool, error) {
+	a_info, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	b_info, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if a_info.Size() != b_info.Size() {
+		return false, nil
+	}
+
+	a_hash, err := hash_file(a)
+	if err != nil {
+		return false, err
+	}
+	b_hash, err := hash_file(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a_hash, b_hash), nil
+}
+
+func hash_file(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// TransferProgress writes how much of a file was copied every progress_interval
+type TransferProgress struct {
+	name    string
+	done    int64
+	total   int64
+	printed time.Time
+}
+
+func (p *TransferProgress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if time.Since(p.printed) >= progress_interval {
+		p.printed = time.Now()
+		fmt.Fprintf(progress_writer(), "\rtransferring %s: %s of %s (%d%%)", p.name, format_size(p.done), format_size(p.total), p.percent())
+	}
+	return len(b), nil
+}
+
+func (p *TransferProgress) finish() {
+	if !p.printed.IsZero() {
+		fmt.Fprintf(progress_writer(), "\rtransferring %s: %s of %s (100%%)\n", p.name, format_size(p.total), format_size(p.total))
+	}
+}
+
+func (p *TransferProgress) percent() int64 {
+	if p.total == 0 {
+		return 100
+	}
+	return p.done * 100 / p.total
+}
+
+func progress_writer() io.Writer {
+	if progress_output == nil {
+		return io.Discard
+	}
+	return progress_output
+}
+
+func format_size(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
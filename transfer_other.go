@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+func is_cross_device(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
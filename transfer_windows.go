@@ -0,0 +1,13 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ERROR_NOT_SAME_DEVICE, returned when moving a file to another drive
+const error_not_same_device syscall.Errno = 17
+
+func is_cross_device(err error) bool {
+	return errors.Is(err, error_not_same_device)
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// a series or movie entry in the terminal ui along with its current rename plan
+type TuiEntry struct {
+	path     string
+	is_movie bool
+	kind     string
+	excluded bool
+	options  AdditionalOptions
+	series   SeriesInfo
+	movie    MovieInfo
+	ops      []RenameOp
+	warnings []string
+	err      error
+}
+
+// text being typed in by the user for an option
+type TuiInput struct {
+	label    string
+	field    string
+	season   int
+	value    string
+	previous AdditionalOptions
+}
+
+type Tui struct {
+	entries     []*TuiEntry
+	cursor      int
+	entry       *TuiEntry
+	file_cursor int
+	season      int
+	input       *TuiInput
+	status      string
+	done        bool
+	apply       bool
+}
+
+func new_Tui(series Series, movies Movies, options AdditionalOptions) *Tui {
+	options = options.with_defaults()
+	tui := &Tui{}
+	for _, kind := range series_kinds() {
+		for _, path := range series.entries[kind] {
+			entry := &TuiEntry{
+				path:    path,
+				kind:    string(kind),
+				options: options,
+			}
+			entry.replan(true)
+			tui.entries = append(tui.entries, entry)
+		}
+	}
+	for _, kind := range movie_kinds() {
+		for _, path := range movies.entries[kind] {
+			entry := &TuiEntry{
+				path:     path,
+				is_movie: true,
+				kind:     string(kind),
+				options:  options,
+			}
+			entry.replan(true)
+			tui.entries = append(tui.entries, entry)
+		}
+	}
+	return tui
+}
+
+// replan computes the rename plan of the entry again after one of its options changed.
+// refetch is needed if the series type or the season 0 option changed since that changes the seasons.
+// files the user excluded stay excluded
+func (entry *TuiEntry) replan(refetch bool) {
+	excluded := make(map[string]bool)
+	for _, op := range entry.ops {
+		if op.skip {
+			excluded[op.old] = true
+		}
+	}
+
+	var warnings bytes.Buffer
+	previous_output := warn_output
+	warn_output = &warnings
+	defer func() {
+		warn_output = previous_output
+		entry.warnings = strings.Split(strings.TrimSpace(warnings.String()), "\n")
+		if len(entry.warnings) == 1 && entry.warnings[0] == "" {
+			entry.warnings = nil
+		}
+	}()
+
+	var err error
+	if entry.is_movie {
+		entry.movie, err = movie_rename_prereqs(entry.path, MovieKind(entry.kind))
+		if err == nil {
+			entry.ops, err = entry.movie.plan()
+		}
+	} else {
+		if refetch {
+			season_options := entry.series.season_options
+			entry.series, err = series_rename_prereqs(entry.path, SeriesKind(entry.kind), entry.options)
+			if err == nil && entry.series.path == entry.path {
+				entry.series.season_options = season_options
+			}
+		}
+		if err == nil {
+			entry.ops, err = entry.series.plan()
+		}
+	}
+
+	entry.err = err
+	if err != nil {
+		entry.ops = nil
+		return
+	}
+	for i := range entry.ops {
+		entry.ops[i].skip = excluded[entry.ops[i].old]
+	}
+}
+
+func (entry *TuiEntry) cycle_type() {
+	types := rule_names(series_rules)
+	if entry.is_movie {
+		types = rule_names(movie_rules)
+	}
+	for i, kind := range types {
+		if kind == entry.kind {
+			entry.kind = types[(i+1)%len(types)]
+			break
+		}
+	}
+	entry.series.season_options = nil
+	entry.replan(true)
+}
+
+// season_options returns the options used for a season of the entry
+func (entry *TuiEntry) season_options(season int) AdditionalOptions {
+	if options, ok := entry.series.season_options[season]; ok {
+		return options
+	}
+	return entry.options
+}
+
+func (entry *TuiEntry) set_season_options(season int, options AdditionalOptions) {
+	if entry.series.season_options == nil {
+		entry.series.season_options = make(map[int]AdditionalOptions)
+	}
+	entry.series.season_options[season] = options
+	entry.replan(false)
+}
+
+func (tui *Tui) selected_op() *RenameOp {
+	if tui.entry == nil || len(tui.entry.ops) == 0 {
+		return nil
+	}
+	if tui.file_cursor >= len(tui.entry.ops) {
+		tui.file_cursor = len(tui.entry.ops) - 1
+	}
+	return &tui.entry.ops[tui.file_cursor]
+}
+
+// selected_season is the season of the selected file. the last selected season is
+// remembered so options can still be changed back when they made the plan fail
+func (tui *Tui) selected_season() int {
+	if op := tui.selected_op(); op != nil {
+		tui.season = op.season
+	}
+	return tui.season
+}
+
+// handle_key updates the ui state for a single key press
+func (tui *Tui) handle_key(key string) {
+	tui.status = ""
+	if key == "ctrl+c" {
+		tui.done = true
+		return
+	}
+	if tui.input != nil {
+		tui.handle_input_key(key)
+		return
+	}
+
+	switch key {
+	case "q":
+		tui.done = true
+		return
+	case "a":
+		tui.done = true
+		tui.apply = true
+		return
+	}
+
+	if tui.entry == nil {
+		tui.handle_list_key(key)
+	} else {
+		tui.handle_entry_key(key)
+	}
+}
+
+func (tui *Tui) handle_list_key(key string) {
+	if len(tui.entries) == 0 {
+		return
+	}
+	selected := tui.entries[tui.cursor]
+	switch key {
+	case "up", "k":
+		if tui.cursor > 0 {
+			tui.cursor--
+		}
+	case "down", "j":
+		if tui.cursor < len(tui.entries)-1 {
+			tui.cursor++
+		}
+	case "t":
+		selected.cycle_type()
+		tui.status = fmt.Sprintf("'%s' is now %s", filepath.Base(selected.path), selected.kind)
+	case "x", " ":
+		selected.excluded = !selected.excluded
+	case "enter", "right", "l":
+		tui.entry = selected
+		tui.file_cursor = 0
+		tui.season = -1
+	}
+}
+
+func (tui *Tui) handle_entry_key(key string) {
+	entry := tui.entry
+	op := tui.selected_op()
+	switch key {
+	case "esc", "left", "h", "backspace":
+		tui.entry = nil
+	case "up", "k":
+		if tui.file_cursor > 0 {
+			tui.file_cursor--
+		}
+	case "down", "j":
+		if tui.file_cursor < len(entry.ops)-1 {
+			tui.file_cursor++
+		}
+	case "t":
+		entry.cycle_type()
+		tui.status = fmt.Sprintf("'%s' is now %s", filepath.Base(entry.path), entry.kind)
+	case "x", " ":
+		if op != nil {
+			op.skip = !op.skip
+		}
+	case "0":
+		if entry.is_movie {
+			return
+		}
+		s0, _ := entry.options.has_season_0.get()
+		entry.options.has_season_0 = some[bool](!s0)
+		entry.replan(true)
+		tui.status = fmt.Sprintf("specials/extras as season 0: %t", !s0)
+	case "e", "s", "n":
+		season := tui.selected_season()
+		if entry.is_movie || season < 0 {
+			tui.status = "options can only be changed for season episodes"
+			return
+		}
+		options := entry.season_options(season)
+		switch key {
+		case "e":
+			ken, _ := options.keep_ep_nums.get()
+			options.keep_ep_nums = some[bool](!ken)
+			entry.set_season_options(season, options)
+			tui.status = fmt.Sprintf("keep episode numbers for season %d: %t", season, !ken)
+		case "s":
+			sen, _ := options.starting_ep_num.get()
+			tui.input = &TuiInput{label: fmt.Sprintf("starting episode number for season %d", season), field: "starting_ep_num", season: season, value: strconv.Itoa(sen), previous: options}
+		case "n":
+			ns, _ := options.naming_scheme.get()
+			tui.input = &TuiInput{label: fmt.Sprintf("naming scheme for season %d", season), field: "naming_scheme", season: season, value: ns, previous: options}
+		}
+	}
+}
+
+// handle_input_key edits the option being typed in. the plan is updated on every key
+// press while the value is valid so the new names can be previewed
+func (tui *Tui) handle_input_key(key string) {
+	input := tui.input
+	switch key {
+	case "esc":
+		tui.entry.set_season_options(input.season, input.previous)
+		tui.input = nil
+		return
+	case "enter":
+		if err := tui.preview_input(); err != nil {
+			tui.status = err.Error()
+			return
+		}
+		tui.input = nil
+		return
+	case "backspace":
+		if len(input.value) > 0 {
+			_, size := utf8.DecodeLastRuneInString(input.value)
+			input.value = input.value[:len(input.value)-size]
+		}
+	default:
+		if utf8.RuneCountInString(key) != 1 {
+			return
+		}
+		input.value += key
+	}
+
+	if err := tui.preview_input(); err != nil {
+		tui.status = err.Error()
+	}
+}
+
+func (tui *Tui) preview_input() error {
+	input := tui.input
+	options := input.previous
+	switch input.field {
+	case "starting_ep_num":
+		sen, err := strconv.Atoi(strings.TrimSpace(input.value))
+		if err != nil {
+			return fmt.Errorf("starting episode number must be an integer")
+		}
+		options.starting_ep_num = some[int](sen)
+	case "naming_scheme":
+		scheme := strings.TrimSpace(input.value)
+		if scheme == "" {
+			return fmt.Errorf("naming scheme cannot be empty, use 'default' for the default naming scheme")
+		}
+		if scheme != "default" {
+			if err := validate_naming_scheme(scheme); err != nil {
+				// the status line has no room for the caret
+				if scheme_err, ok := err.(*SchemeError); ok {
+					return fmt.Errorf("naming scheme error: %s", scheme_err.short())
+				}
+				return fmt.Errorf("naming scheme error: %s", err)
+			}
+		}
+		options.naming_scheme = some[string](scheme)
+	}
+	tui.entry.set_season_options(input.season, options)
+	return nil
+}
+
+// render draws the current view into lines that fit the given width and height
+func (tui *Tui) render(width int, height int) []string {
+	var lines []string
+	var footer []string
+	cursor_line := 0
+
+	if tui.entry == nil {
+		total, left := 0, 0
+		for _, entry := range tui.entries {
+			for _, op := range entry.ops {
+				total++
+				if op.skip || entry.excluded || op.is_noop() {
+					left++
+				}
+			}
+		}
+		lines = append(lines, fmt.Sprintf("gorn - review rename plan (%d entries, %d of %d files will be renamed)", len(tui.entries), total-left, total))
+		lines = append(lines, "")
+		for i, entry := range tui.entries {
+			mark := "[ ]"
+			if entry.excluded {
+				mark = "[x]"
+			}
+			summary := fmt.Sprintf("%d files", len(entry.ops))
+			if entry.err != nil {
+				summary = "error: " + entry.err.Error()
+			}
+			line := fmt.Sprintf("%s %-28s %-40s %s", mark, entry.kind, filepath.Base(entry.path), summary)
+			if i == tui.cursor {
+				cursor_line = len(lines)
+				line = "\x1b[7m" + pad_line(line, width) + "\x1b[0m"
+			}
+			lines = append(lines, line)
+		}
+		footer = append(footer, "up/down move  enter open  t change type  x exclude entry  a apply  q quit")
+
+	} else {
+		entry := tui.entry
+		lines = append(lines, fmt.Sprintf("%s (%s)", entry.path, entry.kind))
+		if !entry.is_movie {
+			s0, _ := entry.options.has_season_0.get()
+			lines = append(lines, fmt.Sprintf("specials/extras as season 0: %t", s0))
+		}
+		if entry.err != nil {
+			lines = append(lines, "error: "+entry.err.Error())
+		}
+
+		last_season := -2
+		for i, op := range entry.ops {
+			if op.season != last_season {
+				last_season = op.season
+				lines = append(lines, "")
+				if op.season < 0 {
+					lines = append(lines, "movies:")
+				} else {
+					options := entry.season_options(op.season)
+					ken, _ := options.keep_ep_nums.get()
+					sen, _ := options.starting_ep_num.get()
+					ns, _ := options.naming_scheme.get()
+					lines = append(lines, fmt.Sprintf("season %d: keep episode numbers %t | starting episode %d | naming scheme %s", op.season, ken, sen, ns))
+				}
+			}
+
+			// files that already have their new name are marked with [=]
+			mark := "[ ]"
+			if op.skip || entry.excluded {
+				mark = "[x]"
+			} else if op.is_noop() {
+				mark = "[=]"
+			}
+			line := fmt.Sprintf("  %s %s  -->  %s", mark, filepath.Base(op.old), filepath.Base(op.new))
+			if i == tui.file_cursor {
+				cursor_line = len(lines)
+				line = "\x1b[7m" + pad_line(line, width) + "\x1b[0m"
+			}
+			lines = append(lines, line)
+		}
+
+		for _, warning := range entry.warnings {
+			if warning != "[WARNING]" {
+				footer = append(footer, "warning: "+warning)
+			}
+		}
+		if entry.is_movie {
+			footer = append(footer, "up/down move  x exclude file  t change type  esc back  a apply  q quit")
+		} else {
+			footer = append(footer, "up/down move  x exclude file  t change type  0 season 0  esc back")
+			footer = append(footer, "e keep ep nums  s starting ep num  n naming scheme  a apply  q quit")
+		}
+	}
+
+	if tui.input != nil {
+		footer = append([]string{fmt.Sprintf("%s: %s_", tui.input.label, tui.input.value)}, footer...)
+	}
+	if tui.status != "" {
+		footer = append([]string{tui.status}, footer...)
+	}
+	if len(footer) > height/2 {
+		footer = footer[len(footer)-height/2:]
+	}
+
+	// scroll so the cursor is always visible
+	body_height := height - len(footer)
+	if body_height < 1 {
+		body_height = 1
+	}
+	offset := 0
+	if cursor_line >= body_height {
+		offset = cursor_line - body_height + 1
+	}
+	if offset > 0 {
+		lines = lines[offset:]
+	}
+	if len(lines) > body_height {
+		lines = lines[:body_height]
+	}
+	for len(lines) < body_height {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, footer...)
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "\x1b[") {
+			lines[i] = truncate_line(line, width)
+		}
+	}
+	return lines
+}
+
+func truncate_line(line string, width int) string {
+	if utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	runes := []rune(line)
+	return string(runes[:width])
+}
+
+func pad_line(line string, width int) string {
+	line = truncate_line(line, width)
+	return line + strings.Repeat(" ", width-utf8.RuneCountInString(line))
+}
+
+// parse_keys splits raw terminal input into key names
+func parse_keys(input []byte) []string {
+	keys := make([]string, 0)
+	escapes := map[string]string{
+		"\x1b[A": "up",
+		"\x1b[B": "down",
+		"\x1b[C": "right",
+		"\x1b[D": "left",
+		"\x1bOA": "up",
+		"\x1bOB": "down",
+		"\x1bOC": "right",
+		"\x1bOD": "left",
+	}
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			if len(input) >= 3 && escapes[string(input[:3])] != "" {
+				keys = append(keys, escapes[string(input[:3])])
+				input = input[3:]
+				continue
+			}
+			keys = append(keys, "esc")
+			input = input[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRune(input)
+		input = input[size:]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		default:
+			keys = append(keys, string(r))
+		}
+	}
+	return keys
+}
+
+// run_tui takes over the terminal until the user applies or quits.
+// renaming happens after the terminal is restored so its output stays visible
+func run_tui(tui *Tui) error {
+	restore, err := enable_raw_mode()
+	if err != nil {
+		return err
+	}
+	// alternate screen and hidden cursor
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	buf := make([]byte, 64)
+	for !tui.done {
+		width, height := terminal_size()
+		var screen strings.Builder
+		screen.WriteString("\x1b[H\x1b[2J")
+		screen.WriteString(strings.Join(tui.render(width, height), "\r\n"))
+		fmt.Print(screen.String())
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, key := range parse_keys(buf[:n]) {
+			tui.handle_key(key)
+			if tui.done {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// apply_plan renames, or links with --link, every file the user did not exclude
+func (tui *Tui) apply_plan() error {
+	for _, entry := range tui.entries {
+		if entry.excluded || entry.err != nil {
+			continue
+		}
+		var options []AdditionalOptions
+		if !entry.is_movie {
+			options = entry.series.resolved_options()
+		}
+		if err := rename_entry(entry.path, entry.is_movie, entry.ops, options); err != nil {
+			return err
+		}
+	}
+	if library != nil {
+		return library.prune()
+	}
+	return nil
+}
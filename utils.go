@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -10,6 +11,24 @@ import (
 	"unicode"
 )
 
+// where warnings are written. the tui swaps this out so warnings don't draw over the screen
+var warn_output io.Writer = os.Stdout
+
+func warn(format string, a ...any) {
+	warn_lock.Lock()
+	defer warn_lock.Unlock()
+	fmt.Fprintf(warn_output, "[WARNING]\n"+format+"\n", a...)
+}
+
+// where `gorn explain` writes the decisions made while classifying an entry. tracing is off when nil
+var trace_output io.Writer
+
+func trace(format string, a ...any) {
+	if trace_output != nil {
+		fmt.Fprintf(trace_output, "\t"+format+"\n", a...)
+	}
+}
+
 func is_media_file(file string) bool {
 	// TODO: find a better way to identify media files
 	media_extensions := map[string]bool {
@@ -23,24 +42,41 @@ func is_media_file(file string) bool {
 	return media_extensions[filepath.Ext(file)]
 }
 
-func has_movie (path string) (bool, error) {
+func has_movie (path string, rule *TypeRule) (bool, error) {
+	movie_dir, err := find_movie_dir(path, rule)
+	return movie_dir != "", err
+}
+
+// find_movie_dir returns the first subdirectory that is neither a season of the series type nor a specials/extras directory
+func find_movie_dir (path string, rule *TypeRule) (string, error) {
 	files, err := os.ReadDir(path)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	seasonal_pattern := regexp.MustCompile(`^(?i)season\s+(\d+)`)
-	specials_pattern := regexp.MustCompile(`^(?i)specials?|extras?|ova`)
-
 	for _, file := range files {
-		// found movie subdir
-		if file.IsDir() && !seasonal_pattern.MatchString(file.Name()) && !specials_pattern.MatchString(file.Name()) {
-			return true, nil
+		if !file.IsDir() {
+			continue
+		}
+		if file.Name() == filepath.Base(path) && rule.SameNameSubdir {
+			trace("'%s': season, same name as the entry", file.Name())
+		} else if rule.subdir_regex != nil && rule.subdir_regex.MatchString(file.Name()) {
+			trace("'%s': season, matches subdir_pattern '%s'", file.Name(), rule.subdir_regex)
+		} else if _, is_season, how, _ := rule.season_of(file.Name()); is_season {
+			trace("'%s': season, %s", file.Name(), how)
+		} else if series_extras_pattern.MatchString(file.Name()) {
+			// the same pattern that finds the specials of a series, so only names that start with
+			// specials/extras/ova/ona are left out. "Bonus Extras" is a movie
+			trace("'%s': specials/extras, matches extras pattern '%s'", file.Name(), series_extras_pattern)
+		} else {
+			// found movie subdir
+			trace("'%s': movie, not a season or specials/extras", file.Name())
+			return file.Name(), nil
 		}
 	}
 
 	// found no movie subdirs
-	return false, nil
+	return "", nil
 }
 
 // valid filename substring formats 
@@ -159,13 +195,16 @@ func is_numeric (s string) bool {
 // 		"title (2016)" --> "title"
 //		"title (2000)" --> "title"
 func clean_title (title string) string {
+	// remove edition and provider id tags. they are put back in the forms of --tag-style
+	title = strip_media_tags(title)
+
 	// remove numbers
 	re := regexp.MustCompile(`\d+\s*([.]|-|_)\s*`)
 	title = re.ReplaceAllString(title, "")
 
 	// remove year in parens
 	re = regexp.MustCompile(`\s*\(\d{4}\)\s*`)
-	title = re.ReplaceAllString(title, "")
+	title = strings.TrimSpace(re.ReplaceAllString(title, " "))
 	return title
 }
 
@@ -209,37 +248,6 @@ func has_only_one_match_group (s string) bool {
 	return matchGroupCount == 1
 }
 
-func parent_token_to_int(s string) (int, error) {
-	// lmao https://regex-vis.com/?r=%3Cparent%28-parent%29*%28%5Cs*%3A%5Cs*%28%28%5Cd+%5Cs*%2C%5Cs*%5Cd+%29%7C%28%27%5B%5E%27%5D*%27%29%29%29%3F%5Cs*%3E&e=0
-	long_form := regexp.MustCompile(`<parent(-parent)*(\s*:\s*((\d+(\s*,\s*\d+)?)|('[^']*')))?\s*>`)
-	// lul https://regex-vis.com/?r=%3Cp%28-%5Cd%2B%29%3F%28%5Cs*%3A%5Cs*%28%28%5Cd%5Cs*%2C%5Cs*%5Cd%29%7C%28%27%5B%5E%27%5D*%27%29%29%29%3F%5Cs*%3E&e=0
-	short_form := regexp.MustCompile(`<p(-\d+)?(\s*:\s*((\d+(\s*,\s*\d+)?)|('[^']*')))?\s*>`)
-	
-	if long_form.MatchString(s) {
-		return strings.Count(s, "parent"), nil
-		
-	} else if short_form.MatchString(s) {
-		// only p
-		single_p := regexp.MustCompile(`p\s*[^-]:?`)
-		if single_p.MatchString(s) {
-			return 1, nil
-		}
-		// p-int
-		match_num := regexp.MustCompile(`p-(\d+)`).FindStringSubmatch(s)
-		if len(match_num) != 2 {
-			return 0, fmt.Errorf("invalid parent token: %s", s)
-		}
-		num, err := strconv.Atoi(match_num[1])
-		if err != nil {
-			return 0, err
-		}
-		return num, nil
-
-	} else {
-		return 0, fmt.Errorf("invalid parent token: %s", s)
-	}
-}
-
 func nth_parent(path string, n int) string {
 	for i := 0; i < n; i++ {
 		path = filepath.Dir(path)